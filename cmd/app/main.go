@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
 
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/config"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/app"
@@ -19,9 +24,14 @@ func main() {
 		log.Fatalf("Failed to create app: %v", err)
 	}
 
+	// SIGINT o SIGTERM apagan el servidor ordenadamente
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	addr := fmt.Sprintf(":%s", config.AppPort)
 	log.Printf("Server starting on %s", addr)
-	if err := app.Run(addr); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	if err := app.Run(ctx, addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
 	}
+	log.Printf("Server stopped")
 }
@@ -4,10 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// MessageLimit es el límite de un tipo de mensaje WebSocket: mensajes por segundo, ráfaga y bytes por frame
+type MessageLimit struct {
+	Rate     float64
+	Burst    int
+	MaxBytes int
+}
+
 type Config struct {
 	DatabaseURL string
 	DBHost      string
@@ -16,6 +26,22 @@ type Config struct {
 	DBPassword  string
 	DBName      string
 	AppPort     string
+
+	// ShutdownTimeout es cuánto se espera a que terminen las peticiones y las salas al apagar
+	ShutdownTimeout time.Duration
+
+	// Capacidad de las salas de colaboración; 0 usa el valor por defecto
+	RoomMaxEditors    int
+	RoomMaxSpectators int
+	RoomMaxWaiting    int
+	RoomIdleGrace     time.Duration // Cuánto se mantiene abierta una sala vacía
+	RoomUndoDepth     int           // Operaciones que cada usuario puede deshacer en una sala
+
+	// RealtimeMessageLimits reemplaza los límites de los mensajes WebSocket por tipo; "*" es la conexión
+	RealtimeMessageLimits map[string]MessageLimit
+
+	// RealtimeBackplane conecta las salas entre instancias: "postgres", "memory" o vacío para una sola instancia
+	RealtimeBackplane string
 }
 
 func LoadConfig() (*Config, error) {
@@ -31,11 +57,90 @@ func LoadConfig() (*Config, error) {
 		DBName:      os.Getenv("DB_NAME"),
 		AppPort:     os.Getenv("APP_PORT"),
 		DatabaseURL: os.Getenv("DATABASE_URL"),
+
+		RealtimeBackplane: os.Getenv("REALTIME_BACKPLANE"),
+	}
+
+	var err error
+	if config.RoomMaxEditors, err = intEnv("ROOM_MAX_EDITORS"); err != nil {
+		return nil, err
+	}
+	if config.RoomMaxSpectators, err = intEnv("ROOM_MAX_SPECTATORS"); err != nil {
+		return nil, err
+	}
+	if config.RoomMaxWaiting, err = intEnv("ROOM_MAX_WAITING"); err != nil {
+		return nil, err
+	}
+	if config.RoomUndoDepth, err = intEnv("ROOM_UNDO_DEPTH"); err != nil {
+		return nil, err
+	}
+
+	if config.ShutdownTimeout, err = durationEnv("SHUTDOWN_TIMEOUT"); err != nil {
+		return nil, err
+	}
+	if config.RoomIdleGrace, err = durationEnv("ROOM_IDLE_GRACE"); err != nil {
+		return nil, err
+	}
+	if config.RealtimeMessageLimits, err = messageLimitsEnv("REALTIME_MESSAGE_LIMITS"); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// intEnv lee una variable entera no negativa; si no está definida retorna 0
+func intEnv(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+// durationEnv lee una duración positiva como "30s" o "2m"; si no está definida retorna 0
+func durationEnv(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 30s", name)
+	}
+	return d, nil
+}
+
+// messageLimitsEnv lee límites con el formato "op=20/60/65536,cursor=30/30/512"
+// (mensajes por segundo/ráfaga/bytes); si no está definida retorna nil
+func messageLimitsEnv(name string) (map[string]MessageLimit, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	limits := make(map[string]MessageLimit)
+	for _, entry := range strings.Split(value, ",") {
+		messageType, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		parts := strings.Split(spec, "/")
+		if !ok || messageType == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("%s: %q must be type=rate/burst/bytes", name, entry)
+		}
+
+		rate, errRate := strconv.ParseFloat(parts[0], 64)
+		burst, errBurst := strconv.Atoi(parts[1])
+		maxBytes, errBytes := strconv.Atoi(parts[2])
+		if errRate != nil || errBurst != nil || errBytes != nil || rate < 0 || burst < 0 || maxBytes < 0 {
+			return nil, fmt.Errorf("%s: %q must have non-negative numbers", name, entry)
+		}
+		limits[messageType] = MessageLimit{Rate: rate, Burst: burst, MaxBytes: maxBytes}
+	}
+	return limits, nil
+}
+
 func (c *Config) GetDBURL() string {
 	if c.DatabaseURL != "" {
 		return c.DatabaseURL
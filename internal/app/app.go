@@ -1,14 +1,20 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/config"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/backplane"
 	v1 "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/controller/http/v1"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/controller/socket"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
 	impl "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services/Impl"
@@ -19,9 +25,21 @@ import (
 	"gorm.io/gorm"
 )
 
+// eventLogCapacity es la cantidad de eventos que se conservan para reanudar streams SSE
+const eventLogCapacity = 1000
+
+// defaultShutdownTimeout es cuánto se espera a que termine el apagado si no se configura otro valor
+const defaultShutdownTimeout = 15 * time.Second
+
 type App struct {
-	router *gin.Engine
-	db     *gorm.DB
+	router          *gin.Engine
+	db              *gorm.DB
+	events          *event.Bus
+	eventLog        *event.Log
+	webhookService  services.WebhookService
+	backplane       backplane.Backplane
+	realtime        *socket.Handler
+	shutdownTimeout time.Duration
 }
 
 func New(config *config.Config) (*App, error) {
@@ -41,17 +59,82 @@ func New(config *config.Config) (*App, error) {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	events := event.NewBus()
 	app := &App{
-		router: r,
-		db:     db,
+		router:   r,
+		db:       db,
+		events:   events,
+		eventLog: event.NewLog(events, eventLogCapacity),
+
+		shutdownTimeout: config.ShutdownTimeout,
+	}
+	if app.shutdownTimeout <= 0 {
+		app.shutdownTimeout = defaultShutdownTimeout
 	}
 
-	app.setupRoutes()
+	if app.backplane, err = setupBackplane(config); err != nil {
+		return nil, fmt.Errorf("failed to setup realtime backplane: %w", err)
+	}
+
+	if err := app.setupRoutes(config); err != nil {
+		return nil, err
+	}
 	return app, nil
 }
 
-func (a *App) Run(addr string) error {
-	return a.router.Run(addr)
+// Run atiende peticiones hasta que ctx se cancela y después apaga la aplicación
+func (a *App) Run(ctx context.Context, addr string) error {
+	// Los streams SSE terminan cuando se cancela el contexto de sus peticiones
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	server := &http.Server{
+		Addr:        addr,
+		Handler:     a.router,
+		BaseContext: func(net.Listener) context.Context { return requests },
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		a.close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down (timeout %s)", a.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	cancelRequests()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+	if err := a.realtime.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Realtime shutdown: %v", err)
+	}
+	a.close()
+	return err
+}
+
+// close detiene los webhooks y el backplane, y cierra la base de datos al final
+func (a *App) close() {
+	a.webhookService.Stop()
+	if a.backplane != nil {
+		if err := a.backplane.Close(); err != nil {
+			log.Printf("Error closing realtime backplane: %v", err)
+		}
+	}
+	if sqlDB, err := a.db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}
 }
 
 func setupDatabase(config *config.Config) (*gorm.DB, error) {
@@ -61,24 +144,87 @@ func setupDatabase(config *config.Config) (*gorm.DB, error) {
 	}
 
 	// Auto-migrate the database
-	if err := db.AutoMigrate(&entity.User{}, &entity.Project{}); err != nil {
+	if err := db.AutoMigrate(&entity.User{}, &entity.Project{}, &entity.ProjectVersion{},
+		&entity.ProjectPublication{}, &entity.ProjectMember{}, &entity.Webhook{}, &entity.WebhookDelivery{}, &entity.ChatMessage{},
+		&entity.RoomSanction{}, &entity.ModerationLog{}, &entity.ConnectionTicket{}); err != nil {
 		return nil, err
 	}
 
 	return db, nil
 }
 
-func (a *App) setupRoutes() {
+// setupBackplane elige cómo se conectan las salas de colaboración entre instancias
+func setupBackplane(config *config.Config) (backplane.Backplane, error) {
+	switch config.RealtimeBackplane {
+	case "":
+		return nil, nil
+	case "memory":
+		return backplane.NewMemory(), nil
+	case "postgres":
+		return backplane.NewPostgres(context.Background(), config.GetDBURL())
+	default:
+		return nil, fmt.Errorf("unknown backplane %q", config.RealtimeBackplane)
+	}
+}
+
+func (a *App) setupRoutes(config *config.Config) error {
+	limits, err := realtimeLimits(config)
+	if err != nil {
+		return fmt.Errorf("invalid REALTIME_MESSAGE_LIMITS: %w", err)
+	}
+
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(a.db)
 	projectRepo := repositories.NewProjectRepository(a.db)
+	publicationRepo := repositories.NewPublicationRepository(a.db)
+	memberRepo := repositories.NewMemberRepository(a.db)
+	webhookRepo := repositories.NewWebhookRepository(a.db)
+	chatRepo := repositories.NewChatRepository(a.db)
+	moderationRepo := repositories.NewModerationRepository(a.db)
+	ticketRepo := repositories.NewTicketRepository(a.db)
 
 	// Initialize services
 	userService := services.NewUserService(userRepo, os.Getenv("JWT_SECRET"))
-	projectService := impl.NewProjectService(projectRepo)
+	projectService := impl.NewProjectService(projectRepo, memberRepo, a.events)
+	publicationService := impl.NewPublicationService(publicationRepo, projectRepo, a.events)
+	contentService := impl.NewContentService(projectRepo, projectService, a.events)
+	chatService := impl.NewChatService(chatRepo, projectService)
+	moderationService := impl.NewModerationService(moderationRepo, projectService, a.events)
+	ticketService := impl.NewTicketService(ticketRepo)
+	a.webhookService = impl.NewWebhookService(webhookRepo, projectRepo, nil)
+
+	// Los webhooks escuchan todos los eventos de dominio
+	a.events.Subscribe(a.webhookService.Dispatch)
+	a.webhookService.Start()
 
 	// Setup routes
-	v1.SetupRoutes(a.router, userService, projectService)
+	v1.SetupRoutes(a.router, userService, projectService, publicationService, contentService, chatService, a.webhookService, a.eventLog)
+
+	a.realtime = socket.SetupRoutes(a.router, socket.Dependencies{
+		Events:     a.events,
+		Users:      userService,
+		Projects:   projectService,
+		Chat:       chatService,
+		Tickets:    ticketService,
+		Moderation: moderationService,
+		Capacity: socket.Capacity{
+			Editors:    config.RoomMaxEditors,
+			Spectators: config.RoomMaxSpectators,
+			Waiting:    config.RoomMaxWaiting,
+		},
+		IdleGrace: config.RoomIdleGrace,
+		UndoDepth: config.RoomUndoDepth,
+		Limits:    limits,
+		Backplane: a.backplane,
+	})
+	return nil
+}
 
-	socket.SetupRoutes(a.router)
+// realtimeLimits aplica sobre los límites por defecto los configurados para los mensajes WebSocket
+func realtimeLimits(config *config.Config) (socket.Limits, error) {
+	overrides := make(map[string]socket.MessageLimit, len(config.RealtimeMessageLimits))
+	for messageType, limit := range config.RealtimeMessageLimits {
+		overrides[messageType] = socket.MessageLimit{Rate: limit.Rate, Burst: limit.Burst, MaxBytes: limit.MaxBytes}
+	}
+	return socket.DefaultLimits.WithOverrides(overrides)
 }
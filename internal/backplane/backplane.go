@@ -0,0 +1,33 @@
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Envelope es un mensaje de una sala que se reparte entre instancias
+type Envelope struct {
+	Node      string          `json:"node"` // Instancia que lo publicó
+	ProjectID string          `json:"project_id"`
+	Kind      string          `json:"kind"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// KindGap es el sobre, sin proyecto, que entrega el backplane cuando pudo perder otros; por
+// ejemplo, lo publicado mientras se reconectaba. Las salas deben volver a sincronizarse.
+const KindGap = "backplane_gap"
+
+// Handler recibe los sobres publicados por todas las instancias, incluida la propia
+type Handler func(Envelope)
+
+// Backplane conecta las salas de un mismo proyecto en distintas instancias. Todas las
+// instancias reciben los sobres en el mismo orden.
+type Backplane interface {
+	Publish(ctx context.Context, envelope Envelope) error
+	// Subscribe registra el receptor; los sobres se entregan de a uno, en orden
+	Subscribe(handler Handler)
+	Close() error
+}
+
+var ErrClosed = errors.New("backplane closed")
@@ -0,0 +1,86 @@
+package backplane
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory reparte los sobres dentro del proceso. Sirve para pruebas y para correr varios hubs
+// en un mismo binario.
+type Memory struct {
+	handlers []Handler
+	queue    []Envelope
+	closed   bool
+	mutex    sync.Mutex
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+// NewMemory crea un backplane en memoria y arranca su goroutine de entrega
+func NewMemory() *Memory {
+	m := &Memory{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// Publish encola el sobre sin bloquear; se entrega en orden a todos los receptores
+func (m *Memory) Publish(ctx context.Context, envelope Envelope) error {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return ErrClosed
+	}
+	m.queue = append(m.queue, envelope)
+	m.mutex.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(handler Handler) {
+	m.mutex.Lock()
+	m.handlers = append(m.handlers, handler)
+	m.mutex.Unlock()
+}
+
+func (m *Memory) Close() error {
+	m.mutex.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.done)
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+func (m *Memory) run() {
+	for {
+		select {
+		case <-m.wake:
+		case <-m.done:
+			return
+		}
+
+		for {
+			m.mutex.Lock()
+			if len(m.queue) == 0 {
+				m.mutex.Unlock()
+				break
+			}
+			envelope := m.queue[0]
+			m.queue = m.queue[1:]
+			handlers := append([]Handler{}, m.handlers...)
+			m.mutex.Unlock()
+
+			for _, handler := range handlers {
+				handler(envelope)
+			}
+		}
+	}
+}
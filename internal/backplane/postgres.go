@@ -0,0 +1,203 @@
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// notifyChannel es el canal de LISTEN/NOTIFY que comparten las instancias
+	notifyChannel = "realtime_rooms"
+	// maxNotifyPayload deja margen bajo el límite de 8000 bytes de NOTIFY; los sobres más
+	// grandes se guardan en realtime_payloads y se notifica solo su id
+	maxNotifyPayload = 7000
+	// payloadRetention es cuánto se conservan los sobres grandes para que los lean las demás instancias
+	payloadRetention = time.Minute
+	// reconnectDelay es la espera antes de volver a escuchar tras perder la conexión
+	reconnectDelay = 2 * time.Second
+)
+
+const createPayloadTable = `CREATE UNLOGGED TABLE IF NOT EXISTS realtime_payloads (
+	id uuid PRIMARY KEY,
+	data bytea NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// notification es lo que viaja por NOTIFY: el sobre completo o la referencia a uno grande
+type notification struct {
+	Ref string `json:"ref,omitempty"`
+	Envelope
+}
+
+// Postgres reparte los sobres con LISTEN/NOTIFY. Postgres entrega las notificaciones en el
+// orden en que se confirmaron, el mismo para todas las instancias.
+type Postgres struct {
+	pool    *pgxpool.Pool
+	handler Handler
+	once    sync.Once
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewPostgres se conecta a la base y crea la tabla de sobres grandes si no existe
+func NewPostgres(ctx context.Context, dsn string) (*Postgres, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, createPayloadTable); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		pool:   pool,
+		ctx:    runCtx,
+		cancel: cancel,
+	}
+
+	p.wg.Add(1)
+	go p.cleanup()
+	return p, nil
+}
+
+func (p *Postgres) Publish(ctx context.Context, envelope Envelope) error {
+	if p.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	payload, err := json.Marshal(notification{Envelope: envelope})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		// El sobre va por la tabla y la notificación solo lleva su id
+		ref := uuid.New()
+		if _, err := p.pool.Exec(ctx, "INSERT INTO realtime_payloads (id, data) VALUES ($1, $2)", ref, payload); err != nil {
+			return err
+		}
+		if payload, err = json.Marshal(notification{Ref: ref.String()}); err != nil {
+			return err
+		}
+	}
+
+	_, err = p.pool.Exec(ctx, "SELECT pg_notify($1, $2)", notifyChannel, string(payload))
+	return err
+}
+
+// Subscribe registra el receptor y empieza a escuchar. Solo se admite un receptor.
+func (p *Postgres) Subscribe(handler Handler) {
+	p.once.Do(func() {
+		p.handler = handler
+		p.wg.Add(1)
+		go p.listen()
+	})
+}
+
+func (p *Postgres) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	p.pool.Close()
+	return nil
+}
+
+// listen mantiene una conexión con LISTEN y entrega cada notificación al receptor. Si la
+// conexión se pierde se reconecta; lo publicado mientras tanto no llega a esta instancia, así
+// que al volver a escuchar entrega un sobre KindGap.
+func (p *Postgres) listen() {
+	defer p.wg.Done()
+
+	for reconnected := false; p.ctx.Err() == nil; reconnected = true {
+		if err := p.listenOnce(reconnected); err != nil && p.ctx.Err() == nil {
+			log.Printf("Backplane: conexión perdida, reintentando: %v", err)
+			select {
+			case <-time.After(reconnectDelay):
+			case <-p.ctx.Done():
+			}
+		}
+	}
+}
+
+func (p *Postgres) listenOnce(reconnected bool) error {
+	conn, err := p.pool.Acquire(p.ctx)
+	if err != nil {
+		return err
+	}
+	// La conexión quedó escuchando; no se devuelve al pool
+	defer conn.Hijack().Close(context.Background())
+
+	if _, err := conn.Exec(p.ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	if reconnected {
+		p.handler(Envelope{Kind: KindGap})
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(p.ctx)
+		if err != nil {
+			return err
+		}
+
+		var msg notification
+		if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			log.Printf("Backplane: notificación inválida: %v", err)
+			continue
+		}
+		if ref := msg.Ref; ref != "" {
+			if msg, err = p.fetch(ref); err != nil {
+				log.Printf("Backplane: no se pudo leer el sobre %s: %v", ref, err)
+				continue
+			}
+		}
+		p.handler(msg.Envelope)
+	}
+}
+
+// fetch lee un sobre grande guardado en realtime_payloads
+func (p *Postgres) fetch(ref string) (notification, error) {
+	var msg notification
+	var data []byte
+	err := p.pool.QueryRow(p.ctx, "SELECT data FROM realtime_payloads WHERE id = $1", ref).Scan(&data)
+	if err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, err
+	}
+	if msg.Ref != "" {
+		return msg, errors.New("referencia anidada")
+	}
+	return msg, nil
+}
+
+// cleanup borra los sobres grandes que ya tuvieron tiempo de leerse
+func (p *Postgres) cleanup() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(payloadRetention)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, err := p.pool.Exec(p.ctx, "DELETE FROM realtime_payloads WHERE created_at < now() - make_interval(secs => $1)",
+				payloadRetention.Seconds())
+			if err != nil && p.ctx.Err() == nil {
+				log.Printf("Backplane: error limpiando sobres: %v", err)
+			}
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
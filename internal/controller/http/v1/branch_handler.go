@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BranchHandler struct {
+	projectService services.ProjectService
+}
+
+func NewBranchHandler(projectService services.ProjectService) *BranchHandler {
+	return &BranchHandler{
+		projectService: projectService,
+	}
+}
+
+func (h *BranchHandler) Create(c *gin.Context) {
+	var in dto.CreateBranchInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	branch, err := h.projectService.CreateBranch(projectID, userID, in.Name)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, branch)
+}
+
+func (h *BranchHandler) GetAll(c *gin.Context) {
+	projectID, ok := projectMember(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	branches, err := h.projectService.GetBranches(projectID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, branches)
+}
+
+func (h *BranchHandler) Merge(c *gin.Context) {
+	var in dto.MergeBranchInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.projectService.MergeBranch(projectID, userID, &in)
+	if err != nil {
+		respondContentError(c, err)
+		return
+	}
+
+	if !result.Merged {
+		c.JSON(http.StatusConflict, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
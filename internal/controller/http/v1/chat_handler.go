@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChatHandler struct {
+	chatService services.ChatService
+}
+
+func NewChatHandler(chatService services.ChatService) *ChatHandler {
+	return &ChatHandler{
+		chatService: chatService,
+	}
+}
+
+// GetHistory retorna el historial de chat del proyecto. ?before=<id de mensaje> pide la página anterior.
+func (h *ChatHandler) GetHistory(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	before := c.Query("before")
+	if before != "" {
+		if _, err := uuid.Parse(before); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a message id"})
+			return
+		}
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	history, err := h.chatService.GetHistory(projectID, userID, before, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
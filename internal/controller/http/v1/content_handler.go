@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContentHandler struct {
+	contentService services.ContentService
+}
+
+func NewContentHandler(contentService services.ContentService) *ContentHandler {
+	return &ContentHandler{
+		contentService: contentService,
+	}
+}
+
+func (h *ContentHandler) Get(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.contentService.Get(projectID, userID, contentTarget(c))
+	if err != nil {
+		respondMutationError(c, err)
+		return
+	}
+
+	c.Header("ETag", revisionETag(res.Revision))
+	c.JSON(http.StatusOK, res)
+}
+
+// Set reemplaza el subárbol con el cuerpo de la petición, que es el nuevo valor JSON
+func (h *ContentHandler) Set(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+	expected, ok := ifMatchRevision(c)
+	if !ok {
+		return
+	}
+
+	value, err := c.GetRawData()
+	if err != nil || !json.Valid(value) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be a JSON value"})
+		return
+	}
+
+	res, err := h.contentService.Set(projectID, userID, contentTarget(c), value, expected)
+	if err != nil {
+		respondMutationError(c, err)
+		return
+	}
+
+	c.Header("ETag", revisionETag(res.Revision))
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *ContentHandler) Delete(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+	expected, ok := ifMatchRevision(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.contentService.Delete(projectID, userID, contentTarget(c), expected)
+	if err != nil {
+		respondMutationError(c, err)
+		return
+	}
+
+	c.Header("ETag", revisionETag(res.Revision))
+	c.JSON(http.StatusOK, res)
+}
+
+// InsertChild agrega un nodo a los hijos del nodo direccionado
+func (h *ContentHandler) InsertChild(c *gin.Context) {
+	var in dto.InsertNodeInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+	expected, ok := ifMatchRevision(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.contentService.InsertChild(projectID, userID, contentTarget(c), &in, expected)
+	if err != nil {
+		respondMutationError(c, err)
+		return
+	}
+
+	c.Header("ETag", revisionETag(res.Revision))
+	c.JSON(http.StatusCreated, res)
+}
+
+// contentTarget lee el destino de la ruta: /content/*pointer o /nodes/:node_id
+func contentTarget(c *gin.Context) dto.ContentTarget {
+	if nodeID := c.Param("node_id"); nodeID != "" {
+		return dto.ContentTarget{NodeID: nodeID}
+	}
+	// "/content/" direcciona la raíz del documento
+	pointer := c.Param("pointer")
+	if pointer == "/" {
+		pointer = ""
+	}
+	return dto.ContentTarget{Pointer: pointer}
+}
+
+// ifMatchRevision lee la revisión esperada del header If-Match (0 si no viene)
+func ifMatchRevision(c *gin.Context) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	revision, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be a project revision"})
+		return 0, false
+	}
+	return revision, true
+}
+
+func revisionETag(revision int) string {
+	return `"` + strconv.Itoa(revision) + `"`
+}
+
+// respondMutationError agrega a respondContentError los errores de las rutas del documento
+func respondMutationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrContentPathNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrRevisionConflict):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrDuplicateNodeID):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidNode), errors.Is(err, services.ErrRootNotDeletable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		respondContentError(c, err)
+	}
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"gorm.io/gorm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondServiceError traduce los errores comunes de los servicios a respuestas HTTP
+func respondServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "you don't have permission to perform this action"})
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrUnknownEvent),
+		errors.Is(err, services.ErrNotABranch), errors.Is(err, services.ErrInvalidResolution):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// respondContentError agrega a respondServiceError los errores de documentos inválidos
+func respondContentError(c *gin.Context, err error) {
+	var syntaxErr *json.SyntaxError
+	if errors.Is(err, content.ErrInvalidDocument) || errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	respondServiceError(c, err)
+}
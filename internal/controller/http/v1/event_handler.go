@@ -0,0 +1,196 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sseHeartbeatPeriod = 15 * time.Second
+	sseRetryMillis     = 3000
+)
+
+type EventHandler struct {
+	projectService services.ProjectService
+	events         *event.Log
+}
+
+func NewEventHandler(projectService services.ProjectService, events *event.Log) *EventHandler {
+	return &EventHandler{
+		projectService: projectService,
+		events:         events,
+	}
+}
+
+// ProjectEvents transmite por SSE los eventos de un proyecto
+func (h *EventHandler) ProjectEvents(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	role, err := h.projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you don't have access to this project"})
+		return
+	}
+
+	h.stream(c, func(e event.Event) (send, end bool) {
+		if e.ProjectID != projectID {
+			return false, false
+		}
+		switch {
+		case e.Type == event.ProjectDeleted:
+			return true, true
+		case e.Type == event.MemberRemoved && eventMemberID(e) == userID.String():
+			// Se perdió el acceso al proyecto
+			return true, true
+		}
+		return true, false
+	})
+}
+
+// MyEvents transmite por SSE los eventos de todos los proyectos a los que el usuario tiene acceso
+func (h *EventHandler) MyEvents(c *gin.Context) {
+	owner, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := owner.(uuid.UUID).String()
+
+	ids, err := h.projectService.GetAccessibleProjectIDs(owner.(uuid.UUID))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	projects := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		projects[id] = true
+	}
+
+	// El conjunto de proyectos se mantiene al día con los propios eventos de membresía
+	h.stream(c, func(e event.Event) (send, end bool) {
+		switch {
+		case e.Type == event.ProjectCreated && e.UserID == userID:
+			projects[e.ProjectID] = true
+		case e.Type == event.MemberAdded && eventMemberID(e) == userID:
+			projects[e.ProjectID] = true
+		case e.Type == event.MemberRemoved && eventMemberID(e) == userID:
+			delete(projects, e.ProjectID)
+			return true, false
+		case e.Type == event.ProjectDeleted && projects[e.ProjectID]:
+			delete(projects, e.ProjectID)
+			return true, false
+		}
+		return projects[e.ProjectID], false
+	})
+}
+
+// stream escribe los eventos aceptados por filter hasta que el cliente se desconecte.
+// Si llega el header Last-Event-ID se reenvían primero los eventos perdidos desde el log.
+func (h *EventHandler) stream(c *gin.Context, filter func(event.Event) (send, end bool)) {
+	live, cancel := h.events.Watch()
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+
+	var lastSeq uint64
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		seq, err := strconv.ParseUint(lastEventID, 10, 64)
+		backlog, complete := h.events.Since(seq)
+		if err != nil || !complete {
+			// Parte del historial ya no está disponible: el cliente debe recargar su estado
+			lastSeq = h.events.LastSeq()
+			writeSSE(w, lastSeq, "resync", gin.H{"reason": "event log no longer contains the requested id"})
+			backlog = nil
+		}
+		for _, entry := range backlog {
+			if entry.Seq <= lastSeq {
+				continue
+			}
+			lastSeq = entry.Seq
+			send, end := filter(entry.Event)
+			if send {
+				writeSSE(w, entry.Seq, entry.Event.Type, entry.Event)
+			}
+			if end {
+				w.Flush()
+				return
+			}
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case entry, ok := <-live:
+			if !ok {
+				// El cliente se quedó atrás; al reconectar reanudará con Last-Event-ID
+				return
+			}
+			if entry.Seq <= lastSeq {
+				continue
+			}
+			lastSeq = entry.Seq
+
+			send, end := filter(entry.Event)
+			if send {
+				writeSSE(w, entry.Seq, entry.Event.Type, entry.Event)
+				w.Flush()
+			}
+			if end {
+				return
+			}
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			w.Flush()
+		}
+	}
+}
+
+func writeSSE(w io.Writer, id uint64, name string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, name, payload)
+}
+
+// eventMemberID extrae el usuario afectado de un evento de membresía
+func eventMemberID(e event.Event) string {
+	if data, ok := e.Data.(map[string]interface{}); ok {
+		if id, ok := data["member_id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MemberHandler struct {
+	projectService services.ProjectService
+}
+
+func NewMemberHandler(projectService services.ProjectService) *MemberHandler {
+	return &MemberHandler{
+		projectService: projectService,
+	}
+}
+
+func (h *MemberHandler) GetAll(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	role, err := h.projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you don't have access to this project"})
+		return
+	}
+
+	members, err := h.projectService.GetMembers(projectID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *MemberHandler) Add(c *gin.Context) {
+	var in dto.AddMemberInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID, actorID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	member, err := h.projectService.AddMember(projectID, actorID, uuid.MustParse(in.UserID), in.Role)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *MemberHandler) Remove(c *gin.Context) {
+	projectID, actorID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return
+	}
+
+	if err := h.projectService.RemoveMember(projectID, actorID, memberID); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed successfully"})
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// projectAndUser lee el :id del proyecto y el usuario autenticado, respondiendo el error si falta alguno
+func projectAndUser(c *gin.Context) (string, uuid.UUID, bool) {
+	projectID := c.Param("id")
+	if _, err := uuid.Parse(projectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return "", uuid.Nil, false
+	}
+
+	owner, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", uuid.Nil, false
+	}
+
+	return projectID, owner.(uuid.UUID), true
+}
+
+// projectMember lee el :id del proyecto y verifica que el usuario autenticado tenga acceso a él
+func projectMember(c *gin.Context, projectService services.ProjectService) (string, bool) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return "", false
+	}
+
+	role, err := projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return "", false
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you don't have access to this project"})
+		return "", false
+	}
+	return projectID, true
+}
+
+// uuidParam lee un parámetro de ruta que debe ser un UUID
+func uuidParam(c *gin.Context, name string) (string, bool) {
+	value := c.Param(name)
+	if _, err := uuid.Parse(value); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID format"})
+		return "", false
+	}
+	return value, true
+}
@@ -3,9 +3,11 @@ package v1
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
@@ -132,3 +134,94 @@ func (h *ProjectHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "project deleted successfully"})
 }
+
+func (h *ProjectHandler) GetVersions(c *gin.Context) {
+	id, ok := projectMember(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	versions, err := h.projectService.GetProjectVersions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+func (h *ProjectHandler) GetVersion(c *gin.Context) {
+	id, ok := projectMember(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	version, err := h.projectService.GetProjectVersion(id, revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+func (h *ProjectHandler) Diff(c *gin.Context) {
+	id, ok := projectMember(c, h.projectService)
+	if !ok {
+		return
+	}
+
+	from, err := optionalRevision(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from revision"})
+		return
+	}
+	to, err := optionalRevision(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to revision"})
+		return
+	}
+
+	diff, err := h.projectService.DiffProjectVersions(id, from, to)
+	if err != nil {
+		respondContentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// DiffDocuments compara dos documentos arbitrarios enviados en el body
+func (h *ProjectHandler) DiffDocuments(c *gin.Context) {
+	var in dto.DiffDocumentsInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := content.DiffDocuments(in.From, in.To)
+	if err != nil {
+		respondContentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// optionalRevision interpreta un número de revisión opcional; vacío equivale a 0
+func optionalRevision(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, errors.New("invalid revision")
+	}
+	return revision, nil
+}
@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PublicationHandler struct {
+	publicationService services.PublicationService
+}
+
+func NewPublicationHandler(publicationService services.PublicationService) *PublicationHandler {
+	return &PublicationHandler{
+		publicationService: publicationService,
+	}
+}
+
+func (h *PublicationHandler) Publish(c *gin.Context) {
+	var in dto.PublishProjectInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	publication, err := h.publicationService.Publish(projectID, userID, in.Revision)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, publication)
+}
+
+func (h *PublicationHandler) Unpublish(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	publication, err := h.publicationService.Unpublish(projectID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, publication)
+}
+
+func (h *PublicationHandler) Get(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	publication, err := h.publicationService.GetPublication(projectID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, publication)
+}
+
+// GetPublic sirve la copia publicada sin autenticación
+func (h *PublicationHandler) GetPublic(c *gin.Context) {
+	publication, err := h.publicationService.GetPublishedBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%d-%d"`, publication.Slug, publication.Revision, publication.UpdatedAt.Unix())
+	c.Header("Cache-Control", "public, max-age=60")
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PublicProjectResponse{
+		Slug:        publication.Slug,
+		Title:       publication.Title,
+		Description: publication.Description,
+		Revision:    publication.Revision,
+		Content:     json.RawMessage(publication.Content),
+		PublishedAt: publication.PublishedAt,
+	})
+}
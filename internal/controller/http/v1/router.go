@@ -3,13 +3,14 @@ package v1
 import (
 	"os"
 
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/middleware"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, userService services.UserService, projectService services.ProjectService) {
+func SetupRoutes(router *gin.Engine, userService services.UserService, projectService services.ProjectService, publicationService services.PublicationService, contentService services.ContentService, chatService services.ChatService, webhookService services.WebhookService, eventLog *event.Log) {
 	jwt := os.Getenv("JWT_SECRET")
 	v1 := router.Group("/api/v1")
 	{
@@ -31,6 +32,13 @@ func SetupRoutes(router *gin.Engine, userService services.UserService, projectSe
 		}
 
 		projectHandler := NewProjectHandler(projectService)
+		publicationHandler := NewPublicationHandler(publicationService)
+		contentHandler := NewContentHandler(contentService)
+		memberHandler := NewMemberHandler(projectService)
+		chatHandler := NewChatHandler(chatService)
+		webhookHandler := NewWebhookHandler(webhookService)
+		eventHandler := NewEventHandler(projectService, eventLog)
+		branchHandler := NewBranchHandler(projectService)
 		projects := v1.Group("/projects")
 		projects.Use(middleware.JWTMiddleware(jwt))
 		{
@@ -39,6 +47,54 @@ func SetupRoutes(router *gin.Engine, userService services.UserService, projectSe
 			projects.GET("/", projectHandler.GetAll)
 			projects.PATCH("/:id", projectHandler.Update)
 			projects.DELETE("/:id", projectHandler.Delete)
+			projects.GET("/:id/versions", projectHandler.GetVersions)
+			projects.GET("/:id/versions/:revision", projectHandler.GetVersion)
+			projects.GET("/:id/diff", projectHandler.Diff)
+
+			projects.GET("/:id/content/*pointer", contentHandler.Get)
+			projects.PUT("/:id/content/*pointer", contentHandler.Set)
+			projects.DELETE("/:id/content/*pointer", contentHandler.Delete)
+			projects.POST("/:id/content/*pointer", contentHandler.InsertChild)
+			projects.GET("/:id/nodes/:node_id", contentHandler.Get)
+			projects.PUT("/:id/nodes/:node_id", contentHandler.Set)
+			projects.DELETE("/:id/nodes/:node_id", contentHandler.Delete)
+			projects.POST("/:id/nodes/:node_id", contentHandler.InsertChild)
+
+			projects.POST("/:id/branches", branchHandler.Create)
+			projects.GET("/:id/branches", branchHandler.GetAll)
+			projects.POST("/:id/merge", branchHandler.Merge)
+
+			projects.GET("/:id/publication", publicationHandler.Get)
+			projects.POST("/:id/publication", publicationHandler.Publish)
+			projects.DELETE("/:id/publication", publicationHandler.Unpublish)
+
+			projects.GET("/:id/members", memberHandler.GetAll)
+			projects.POST("/:id/members", memberHandler.Add)
+			projects.DELETE("/:id/members/:user_id", memberHandler.Remove)
+
+			projects.GET("/:id/chat", chatHandler.GetHistory)
+
+			projects.GET("/:id/webhooks", webhookHandler.GetAll)
+			projects.POST("/:id/webhooks", webhookHandler.Create)
+			projects.DELETE("/:id/webhooks/:webhook_id", webhookHandler.Delete)
+			projects.GET("/:id/webhooks/:webhook_id/deliveries", webhookHandler.GetDeliveries)
+			projects.POST("/:id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+
+			projects.GET("/:id/events", eventHandler.ProjectEvents)
+		}
+
+		v1.POST("/diff", middleware.JWTMiddleware(jwt), projectHandler.DiffDocuments)
+
+		me := v1.Group("/me")
+		me.Use(middleware.JWTMiddleware(jwt))
+		{
+			me.GET("/events", eventHandler.MyEvents)
+		}
+
+		// Páginas públicas de solo lectura, sin JWT
+		public := router.Group("/public")
+		{
+			public.GET("/projects/:slug", publicationHandler.GetPublic)
 		}
 	}
 }
@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookHandler(webhookService services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var in dto.CreateWebhookInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(projectID, userID, &in)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) GetAll(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.webhookService.GetWebhooks(projectID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	webhookID, ok := uuidParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(projectID, webhookID, userID); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted successfully"})
+}
+
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	webhookID, ok := uuidParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(projectID, webhookID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	projectID, userID, ok := projectAndUser(c)
+	if !ok {
+		return
+	}
+
+	webhookID, ok := uuidParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := uuidParam(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(projectID, webhookID, deliveryID, userID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
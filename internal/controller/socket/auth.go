@@ -0,0 +1,106 @@
+package socket
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/middleware"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+)
+
+const (
+	// ticketTTL es la vigencia de un ticket de conexión; solo sirve para un upgrade
+	ticketTTL = 30 * time.Second
+
+	// tokenSubprotocol es el marcador que precede al JWT en Sec-WebSocket-Protocol:
+	// "Sec-WebSocket-Protocol: access_token, <jwt>"
+	tokenSubprotocol = "access_token"
+)
+
+var ErrUnauthenticated = errors.New("usuario no autenticado")
+
+// Authenticator verifica las conexiones con el mismo JWT que usa la API REST
+type Authenticator struct {
+	jwtSecret string
+	users     services.UserService
+	tickets   services.TicketService
+}
+
+// NewAuthenticator crea un autenticador que valida tokens firmados con jwtSecret
+func NewAuthenticator(jwtSecret string, users services.UserService, tickets services.TicketService) *Authenticator {
+	return &Authenticator{
+		jwtSecret: jwtSecret,
+		users:     users,
+		tickets:   tickets,
+	}
+}
+
+// IssueTicket genera un ticket de un solo uso para abrir el WebSocket sin exponer el JWT en la URL
+func (a *Authenticator) IssueTicket(userID uuid.UUID) (string, time.Time, error) {
+	return a.tickets.Issue(userID, ticketTTL)
+}
+
+// Authenticate identifica al usuario de la petición. Acepta, en orden, el header
+// Authorization, el JWT en Sec-WebSocket-Protocol o un ticket en el query string.
+// Si el token vino como subprotocolo retorna el subprotocolo que se debe aceptar.
+func (a *Authenticator) Authenticate(r *http.Request) (*entity.User, string, error) {
+	var (
+		userID      uuid.UUID
+		subprotocol string
+		err         error
+	)
+
+	switch {
+	case strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "):
+		userID, _, err = middleware.ParseToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), a.jwtSecret)
+
+	case protocolToken(r) != "":
+		userID, _, err = middleware.ParseToken(protocolToken(r), a.jwtSecret)
+		subprotocol = tokenSubprotocol
+
+	case r.URL.Query().Get("ticket") != "":
+		userID, err = a.tickets.Redeem(r.URL.Query().Get("ticket"))
+
+	default:
+		err = ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, "", ErrUnauthenticated
+	}
+
+	// El usuario debe seguir existiendo; un token de una cuenta eliminada no sirve
+	user, err := a.users.GetUserByID(userID.String())
+	if err != nil {
+		return nil, "", ErrUnauthenticated
+	}
+	return user, subprotocol, nil
+}
+
+// protocolToken extrae el JWT enviado como "access_token, <jwt>" en Sec-WebSocket-Protocol
+func protocolToken(r *http.Request) string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+
+	for i, p := range protocols {
+		if p == tokenSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// displayName es el nombre con el que el usuario aparece en las salas
+func displayName(user *entity.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.Email
+}
@@ -0,0 +1,245 @@
+package socket
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// awarenessThrottle agrupa las actualizaciones de presencia en un solo frame por intervalo
+	awarenessThrottle = 100 * time.Millisecond
+	// awarenessSweepPeriod es cada cuánto se revisan los estados vencidos
+	awarenessSweepPeriod = 5 * time.Second
+	// awarenessTTL es el tiempo sin actualizaciones tras el cual el usuario pasa a "idle"
+	awarenessTTL = 30 * time.Second
+	// typingTTL es cuánto dura el estado "typing" si el cliente no lo renueva
+	typingTTL = 5 * time.Second
+)
+
+const MessageAwareness = "awareness" // Cliente -> servidor: estado propio. Servidor -> sala: cambios de presencia
+
+// Estados de actividad de un colaborador
+const (
+	StatusActive = "active"
+	StatusTyping = "typing"
+	StatusIdle   = "idle"
+)
+
+// presenceColors es la paleta con la que se distingue a cada colaborador en el editor
+var presenceColors = []string{
+	"#E53935", "#1E88E5", "#43A047", "#FB8C00",
+	"#8E24AA", "#00ACC1", "#F4511E", "#3949AB",
+}
+
+type Pointer struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Presence es el estado de awareness de una conexión dentro de la sala
+type Presence struct {
+	ClientID  string    `json:"client_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role,omitempty"`
+	Spectator bool      `json:"spectator,omitempty"`
+	Color     string    `json:"color"`
+	Status    string    `json:"status"`
+	Screen    string    `json:"screen,omitempty"`
+	Pointer   *Pointer  `json:"pointer,omitempty"`
+	Selection []string  `json:"selection"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AwarenessPayload actualiza el estado propio; los campos omitidos no cambian
+type AwarenessPayload struct {
+	Status *string `json:"status,omitempty"`
+	Screen *string `json:"screen,omitempty"`
+}
+
+func (p *AwarenessPayload) Validate() error {
+	if p.Status != nil && *p.Status != StatusActive && *p.Status != StatusTyping && *p.Status != StatusIdle {
+		return errors.New("status debe ser active, typing o idle")
+	}
+	if p.Screen != nil && len(*p.Screen) > maxScreenNameBytes {
+		return errors.New("screen es demasiado largo")
+	}
+	return nil
+}
+
+// addPresence crea el estado del cliente con un color libre. Requiere r.mutex tomado.
+func (r *Room) addPresence(client *Client) {
+	used := make(map[string]bool, len(r.presence))
+	for _, p := range r.presence {
+		used[p.Color] = true
+	}
+	color := presenceColors[len(r.presence)%len(presenceColors)]
+	for _, candidate := range presenceColors {
+		if !used[candidate] {
+			color = candidate
+			break
+		}
+	}
+
+	r.presence[client] = &Presence{
+		ClientID:  client.ID,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		Role:      client.Role,
+		Spectator: client.Spectator,
+		Color:     color,
+		Status:    StatusActive,
+		Selection: []string{},
+		UpdatedAt: time.Now(),
+	}
+	r.presenceDirty[client] = true
+	r.signalAwareness()
+}
+
+// removePresence elimina el estado del cliente y avisa a la sala. Requiere r.mutex tomado.
+func (r *Room) removePresence(client *Client) {
+	if _, ok := r.presence[client]; !ok {
+		return
+	}
+	delete(r.presence, client)
+	delete(r.presenceDirty, client)
+	r.presenceRemoved = append(r.presenceRemoved, client.ID)
+	r.signalAwareness()
+}
+
+// updatePresence aplica un cambio al estado del cliente; el envío a la sala se agrupa
+func (r *Room) updatePresence(client *Client, update func(p *Presence)) {
+	r.mutex.Lock()
+	p, ok := r.presence[client]
+	if ok {
+		if p.Status == StatusIdle {
+			// Cualquier actividad saca al usuario de idle
+			p.Status = StatusActive
+		}
+		update(p)
+		p.UpdatedAt = time.Now()
+		r.presenceDirty[client] = true
+	}
+	r.mutex.Unlock()
+
+	if ok {
+		r.signalAwareness()
+	}
+}
+
+func (r *Room) signalAwareness() {
+	select {
+	case r.awarenessSignal <- struct{}{}:
+	default:
+	}
+}
+
+// flushAwareness envía a la sala los estados que cambiaron desde el último envío
+func (r *Room) flushAwareness() {
+	r.mutex.Lock()
+	states := make([]Presence, 0, len(r.presenceDirty))
+	for client := range r.presenceDirty {
+		if p, ok := r.presence[client]; ok {
+			states = append(states, copyPresence(p))
+		}
+	}
+	removed := append([]string{}, r.presenceRemoved...)
+	r.presenceDirty = make(map[*Client]bool)
+	r.presenceRemoved = nil
+	r.mutex.Unlock()
+
+	if len(states) == 0 && len(removed) == 0 {
+		return
+	}
+	r.broadcastAwareness(states, removed)
+	r.publish(kindPresence, presenceUpdate{States: states, Removed: removed})
+}
+
+// broadcastAwareness envía un frame de awareness a los clientes de esta instancia
+func (r *Room) broadcastAwareness(states []Presence, removed []string) {
+	if len(states) == 0 && len(removed) == 0 {
+		return
+	}
+	if states == nil {
+		states = []Presence{}
+	}
+	if removed == nil {
+		removed = []string{}
+	}
+	r.broadcastLocal(Message{
+		Type:      MessageAwareness,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"states":  states,
+			"removed": removed,
+		},
+	})
+}
+
+// expireAwareness vence los estados que no se renovaron a tiempo
+func (r *Room) expireAwareness() {
+	now := time.Now()
+	changed := false
+
+	r.mutex.Lock()
+	for client, p := range r.presence {
+		age := now.Sub(p.UpdatedAt)
+		switch {
+		case p.Status != StatusIdle && age > awarenessTTL:
+			p.Status = StatusIdle
+			p.Pointer = nil
+		case p.Status == StatusTyping && age > typingTTL:
+			p.Status = StatusActive
+		default:
+			continue
+		}
+		r.presenceDirty[client] = true
+		changed = true
+	}
+	r.mutex.Unlock()
+
+	if changed {
+		r.signalAwareness()
+	}
+}
+
+// sendAwarenessSnapshot envía al cliente el estado de todos los colaboradores
+func (r *Room) sendAwarenessSnapshot(client *Client) {
+	r.sendTo(client, Message{
+		Type:      MessageAwareness,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"snapshot": true,
+			"states":   r.GetConnectedUsers(),
+			"removed":  []string{},
+		},
+	})
+}
+
+// GetConnectedUsers retorna el estado de awareness de cada conexión de la sala, incluidas
+// las de otras instancias
+func (r *Room) GetConnectedUsers() []Presence {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	users := make([]Presence, 0, len(r.presence))
+	for _, p := range r.presence {
+		users = append(users, copyPresence(p))
+	}
+	for _, remote := range r.cluster.remote {
+		for _, p := range remote.states {
+			users = append(users, copyPresence(&p))
+		}
+	}
+	return users
+}
+
+func copyPresence(p *Presence) Presence {
+	out := *p
+	out.Selection = append([]string{}, p.Selection...)
+	if p.Pointer != nil {
+		pointer := *p.Pointer
+		out.Pointer = &pointer
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package socket
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// Umbrales de ocupación del canal de envío de un cliente, en porcentaje
+const (
+	// laggingPercent marca al cliente como atrasado: se avisa y se dejan de enviar frames de awareness
+	laggingPercent = 75
+	// normalDropPercent es desde donde se descartan también los frames comunes, para dejar
+	// lugar a las operaciones de edición
+	normalDropPercent = 90
+	// recoveredPercent es la ocupación bajo la cual el cliente deja de estar atrasado
+	recoveredPercent = 25
+)
+
+const MessageLagging = "lagging" // Servidor -> cliente: su conexión no da abasto o ya se recuperó
+
+var (
+	ErrRoomBusy   = errors.New("la sala no da abasto, mensaje descartado")
+	ErrRoomClosed = errors.New("la sala está cerrada")
+)
+
+// framePriorityLevel ordena qué frames se descartan primero cuando un cliente se atrasa
+type framePriorityLevel int
+
+const (
+	priorityPresence framePriorityLevel = iota // Se reemplaza con un snapshot al recuperarse
+	priorityNormal                             // Chat y avisos de la sala; el cliente los recupera con resume_from
+	priorityEdit                               // Operaciones, documento y respuestas; nunca se descartan
+)
+
+func framePriority(messageType string) framePriorityLevel {
+	switch messageType {
+	case MessageAwareness:
+		return priorityPresence
+	case MessageOp, MessageOpRejected, MessageDocument, MessageResyncRequired,
+		MessageError, MessagePong, MessageQueued, MessageLagging, MessageServerShutdown,
+		MessageMuted, MessageUnmuted, MessageKicked, MessageBanned, MessageHistory,
+		MessageProjectDeleted:
+		return priorityEdit
+	default:
+		return priorityNormal
+	}
+}
+
+// enqueue escribe el frame en el canal del cliente aplicando la política de su prioridad.
+// Si ni siquiera entra una operación el cliente se desconecta. Solo se usa desde run.
+func (r *Room) enqueue(client *Client, frame *frame, priority framePriorityLevel) {
+	used, capacity := len(client.send), cap(client.send)
+
+	switch {
+	case priority == priorityPresence && used*100 >= capacity*laggingPercent:
+		client.presenceStale = true
+		r.dropFrame(client)
+		return
+	case priority == priorityNormal && used*100 >= capacity*normalDropPercent:
+		r.dropFrame(client)
+		return
+	}
+
+	select {
+	case client.send <- frame:
+	default:
+		r.dropFrame(client)
+		r.evict(client)
+		return
+	}
+
+	switch {
+	case !client.lagging && (used+1)*100 >= capacity*laggingPercent:
+		client.lagging = true
+		r.sendLagging(client, true)
+	case client.lagging && used*100 < capacity*recoveredPercent:
+		r.recover(client)
+	}
+}
+
+func (r *Room) dropFrame(client *Client) {
+	client.droppedFrames.Add(1)
+	r.droppedFrames.Add(1)
+}
+
+// sendLagging avisa al cliente que se atrasó o que se recuperó, con los frames que perdió
+func (r *Room) sendLagging(client *Client, lagging bool) {
+	frame, err := newFrame(Message{
+		Type:      MessageLagging,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"lagging":  lagging,
+			"queued":   len(client.send),
+			"capacity": cap(client.send),
+			"dropped":  client.droppedFrames.Load(),
+		},
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case client.send <- frame:
+	default:
+	}
+}
+
+// recover saca al cliente del estado atrasado y le envía el awareness que se descartó
+func (r *Room) recover(client *Client) {
+	client.lagging = false
+	r.sendLagging(client, false)
+	if client.presenceStale {
+		client.presenceStale = false
+		r.sendAwarenessSnapshot(client)
+	}
+}
+
+// checkLagging revisa a los clientes atrasados aunque la sala no tenga tráfico
+func (r *Room) checkLagging() {
+	r.mutex.RLock()
+	var recovered []*Client
+	for client := range r.Clients {
+		if client.lagging && len(client.send)*100 < cap(client.send)*recoveredPercent {
+			recovered = append(recovered, client)
+		}
+	}
+	r.mutex.RUnlock()
+
+	for _, client := range recovered {
+		r.recover(client)
+	}
+}
+
+// evict programa la desconexión de un cliente que no recibe frames. No se hace en el momento
+// porque puede estar en medio de un recorrido de r.Clients.
+func (r *Room) evict(client *Client) {
+	if client.evicted {
+		return
+	}
+	client.evicted = true
+	r.evicted = append(r.evicted, client)
+}
+
+// evictPending desconecta a los clientes marcados con evict
+func (r *Room) evictPending(hub *Hub) {
+	for len(r.evicted) > 0 {
+		client := r.evicted[0]
+		r.evicted = r.evicted[1:]
+
+		if !r.Clients[client] && !r.isWaiting(client) {
+			// Ya salió y su canal está cerrado
+			continue
+		}
+
+		log.Printf("Cliente %s desconectado de la sala %s por no recibir frames (%d descartados)",
+			client.UserID, r.ID, client.droppedFrames.Load())
+		client.closeCode = websocket.CloseTryAgainLater
+		client.closeText = "conexión demasiado lenta"
+		if !r.leaveQueue(client) {
+			r.leave(hub, client)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package socket
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const MessageQueued = "queued" // Servidor -> cliente en espera: posición en la cola de la sala
+
+// Capacity son los límites de conexiones de una sala
+type Capacity struct {
+	Editors    int // Conexiones que pueden editar
+	Spectators int // Conexiones de solo lectura; no ocupan lugares de editor
+	Waiting    int // Largo máximo de la cola de espera de editores
+}
+
+// DefaultCapacity se usa para los límites que no se configuran
+var DefaultCapacity = Capacity{Editors: 4, Spectators: 30, Waiting: 10}
+
+// withDefaults completa con DefaultCapacity los límites en 0
+func (c Capacity) withDefaults() Capacity {
+	if c.Editors <= 0 {
+		c.Editors = DefaultCapacity.Editors
+	}
+	if c.Spectators <= 0 {
+		c.Spectators = DefaultCapacity.Spectators
+	}
+	if c.Waiting <= 0 {
+		c.Waiting = DefaultCapacity.Waiting
+	}
+	return c
+}
+
+// forProject aplica los límites propios del proyecto sobre los globales
+func (c Capacity) forProject(project *entity.Project) Capacity {
+	if project.MaxEditors > 0 {
+		c.Editors = project.MaxEditors
+	}
+	if project.MaxSpectators > 0 {
+		c.Spectators = project.MaxSpectators
+	}
+	return c
+}
+
+// setCapacity fija los límites de la sala
+func (r *Room) setCapacity(capacity Capacity) {
+	r.mutex.Lock()
+	r.MaxUsers = capacity.Editors
+	r.MaxSpectators = capacity.Spectators
+	r.maxWaiting = capacity.Waiting
+	r.mutex.Unlock()
+}
+
+// occupancy cuenta editores y espectadores conectados. Requiere r.mutex tomado.
+func (r *Room) occupancy() (editors, spectators int) {
+	for client := range r.Clients {
+		if client.Spectator {
+			spectators++
+		} else {
+			editors++
+		}
+	}
+	return editors, spectators
+}
+
+// admit decide si el cliente entra a la sala, espera en la cola o se rechaza. Solo lo llama
+// run, así que la decisión y el alta no compiten con otras conexiones.
+func (r *Room) admit(hub *Hub, client *Client) {
+	r.mutex.RLock()
+	editors, spectators := r.occupancy()
+	hasSeat := (client.Spectator && spectators < r.MaxSpectators) || (!client.Spectator && editors < r.MaxUsers)
+	canWait := !client.Spectator && client.wantsQueue && len(r.waiting) < r.maxWaiting
+	maxUsers, maxSpectators := r.MaxUsers, r.MaxSpectators
+	r.mutex.RUnlock()
+
+	switch {
+	case hasSeat:
+		r.join(hub, client)
+	case canWait:
+		r.mutex.Lock()
+		r.waiting = append(r.waiting, client)
+		r.mutex.Unlock()
+		r.sendQueuePositions()
+		log.Printf("Cliente %s en espera para la sala %s", client.UserID, r.ID)
+	default:
+		reason := fmt.Sprintf("La sala está llena. Máximo %d editores.", maxUsers)
+		if client.Spectator {
+			reason = fmt.Sprintf("La sala está llena. Máximo %d espectadores.", maxSpectators)
+		}
+		r.refuse(client, reason)
+	}
+}
+
+// refuse envía room_full a un cliente que no entró a la sala y cierra su conexión
+func (r *Room) refuse(client *Client, reason string) {
+	message := errorMessage(r.ID, &ErrorPayload{
+		Code:    ErrorCodeRoomFull,
+		Message: reason,
+	})
+	if frame, err := newFrame(message); err == nil {
+		select {
+		case client.send <- frame:
+		default:
+		}
+	}
+	// Cerrar el canal hace que writePump envíe el frame pendiente y cierre el WebSocket
+	client.closeCode = websocket.CloseTryAgainLater
+	client.closeText = "sala llena"
+	close(client.send)
+}
+
+// admitWaiting ocupa los lugares de editor libres con los clientes en espera, en orden
+func (r *Room) admitWaiting(hub *Hub) {
+	admitted := false
+	for {
+		r.mutex.Lock()
+		editors, _ := r.occupancy()
+		if len(r.waiting) == 0 || editors >= r.MaxUsers {
+			r.mutex.Unlock()
+			break
+		}
+		next := r.waiting[0]
+		r.waiting = r.waiting[1:]
+		r.mutex.Unlock()
+
+		r.join(hub, next)
+		admitted = true
+	}
+	if admitted {
+		r.sendQueuePositions()
+	}
+}
+
+// leaveQueue quita de la cola a un cliente que se desconectó mientras esperaba
+func (r *Room) leaveQueue(client *Client) bool {
+	r.mutex.Lock()
+	i := slices.Index(r.waiting, client)
+	if i < 0 {
+		r.mutex.Unlock()
+		return false
+	}
+	r.waiting = slices.Delete(r.waiting, i, i+1)
+	r.mutex.Unlock()
+
+	close(client.send)
+	r.sendQueuePositions()
+	return true
+}
+
+// sendQueuePositions avisa a cada cliente en espera su posición actual
+func (r *Room) sendQueuePositions() {
+	r.mutex.RLock()
+	waiting := append([]*Client{}, r.waiting...)
+	r.mutex.RUnlock()
+
+	for i, client := range waiting {
+		r.sendTo(client, Message{
+			Type:      MessageQueued,
+			ProjectID: r.ID,
+			Data: map[string]interface{}{
+				"position": i + 1,
+				"waiting":  len(waiting),
+			},
+		})
+	}
+}
+
+// isWaiting indica si el cliente está en la cola. Requiere r.mutex tomado o ser la goroutine de la sala.
+func (r *Room) isWaiting(client *Client) bool {
+	return slices.Contains(r.waiting, client)
+}
+
+// info resume la ocupación de la sala para la API REST
+func (r *Room) info() gin.H {
+	connectedUsers := r.GetConnectedUsers()
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	editors, spectators := r.occupancy()
+	return gin.H{
+		"project_id":       r.ID,
+		"users_count":      len(r.Clients),
+		"editors_count":    editors,
+		"spectators_count": spectators,
+		"waiting_count":    len(r.waiting),
+		"max_users":        r.MaxUsers,
+		"max_spectators":   r.MaxSpectators,
+		"connected_users":  connectedUsers,
+		"is_full":          editors >= r.MaxUsers,
+		"dropped_frames":   r.droppedFrames.Load(),
+		"throttled": gin.H{
+			"messages":    r.throttledMessages.Load(),
+			"clients":     r.throttledClients.Load(),
+			"disconnects": r.throttleDisconnects.Load(),
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package socket
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// chatHistorySize es cuántos mensajes recibe un cliente al unirse
+const chatHistorySize = 50
+
+// Tipos de mensaje del chat persistente
+const (
+	MessageChatEdit    = "chat_edit"    // Cliente -> servidor: editar un mensaje propio
+	MessageChatDelete  = "chat_delete"  // Cliente -> servidor: borrar un mensaje propio
+	MessageChatEdited  = "chat_edited"  // Servidor -> sala: un mensaje fue editado
+	MessageChatDeleted = "chat_deleted" // Servidor -> sala: un mensaje fue borrado
+	MessageChatHistory = "chat_history" // Servidor -> cliente: últimos mensajes al unirse
+)
+
+type ChatEditPayload struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+func (p *ChatEditPayload) Validate() error {
+	if _, err := uuid.Parse(p.ID); err != nil {
+		return errors.New("id inválido")
+	}
+	text := ChatPayload{Text: p.Text}
+	if err := text.Validate(); err != nil {
+		return err
+	}
+	p.Text = text.Text
+	return nil
+}
+
+type ChatDeletePayload struct {
+	ID string `json:"id"`
+}
+
+func (p *ChatDeletePayload) Validate() error {
+	if _, err := uuid.Parse(p.ID); err != nil {
+		return errors.New("id inválido")
+	}
+	return nil
+}
+
+// storeChat guarda el mensaje de chat y lo reemplaza por el registro guardado, para que
+// la sala reciba su id y fecha. Si el hub no tiene chat configurado se retransmite tal cual.
+func (r *Room) storeChat(message *Message) error {
+	if r.hub == nil || r.hub.chat == nil {
+		return nil
+	}
+	chat, ok := message.Data.(*ChatPayload)
+	if !ok {
+		return nil
+	}
+	userID, err := uuid.Parse(message.UserID)
+	if err != nil {
+		return err
+	}
+
+	stored, err := r.hub.chat.SendMessage(r.ID, userID, message.Username, chat.Text)
+	if err != nil {
+		return err
+	}
+	message.Data = stored
+	return nil
+}
+
+// discardChat borra del historial un mensaje de chat que se guardó pero no llegó a la sala
+func (r *Room) discardChat(message Message) {
+	stored, ok := message.Data.(*entity.ChatMessage)
+	if !ok || r.hub == nil || r.hub.chat == nil {
+		return
+	}
+	if _, err := r.hub.chat.DeleteMessage(r.ID, stored.ID.String(), stored.UserID); err != nil {
+		log.Printf("Error borrando el mensaje de chat descartado de la sala %s: %v", r.ID, err)
+	}
+}
+
+// sendChatHistory envía al cliente los últimos mensajes del chat del proyecto
+func (r *Room) sendChatHistory(client *Client) {
+	if r.hub == nil || r.hub.chat == nil {
+		return
+	}
+	messages, err := r.hub.chat.GetRecent(r.ID, chatHistorySize)
+	if err != nil {
+		log.Printf("Error cargando el historial de chat de la sala %s: %v", r.ID, err)
+		return
+	}
+	r.sendTo(client, Message{
+		Type:      MessageChatHistory,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"messages": messages,
+		},
+	})
+}
+
+func editChat(room *Room, c *Client, p payload) {
+	edit := p.(*ChatEditPayload)
+	if room.hub.chat == nil {
+		return
+	}
+	userID, _ := uuid.Parse(c.UserID)
+
+	message, err := room.hub.chat.EditMessage(room.ID, edit.ID, userID, edit.Text)
+	if err != nil {
+		room.Reply(c, chatError(room.ID, MessageChatEdit, err))
+		return
+	}
+	err = room.BroadcastToRoom(Message{
+		Type:      MessageChatEdited,
+		Data:      message,
+		ProjectID: room.ID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+	})
+	if err != nil {
+		room.Reply(c, broadcastError(room.ID, MessageChatEdit, err))
+	}
+}
+
+func deleteChat(room *Room, c *Client, p payload) {
+	del := p.(*ChatDeletePayload)
+	if room.hub.chat == nil {
+		return
+	}
+	userID, _ := uuid.Parse(c.UserID)
+
+	if _, err := room.hub.chat.DeleteMessage(room.ID, del.ID, userID); err != nil {
+		room.Reply(c, chatError(room.ID, MessageChatDelete, err))
+		return
+	}
+	err := room.BroadcastToRoom(Message{
+		Type:      MessageChatDeleted,
+		Data:      map[string]interface{}{"id": del.ID},
+		ProjectID: room.ID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+	})
+	if err != nil {
+		room.Reply(c, broadcastError(room.ID, MessageChatDelete, err))
+	}
+}
+
+func chatError(projectID, messageType string, err error) Message {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errorMessage(projectID, &ErrorPayload{Code: ErrorCodeNotFound, Message: "el mensaje no existe", Type: messageType})
+	case errors.Is(err, services.ErrForbidden):
+		return errorMessage(projectID, &ErrorPayload{Code: ErrorCodeForbidden, Message: "solo puedes modificar tus propios mensajes", Type: messageType})
+	default:
+		log.Printf("Error en %s de la sala %s: %v", messageType, projectID, err)
+		return errorMessage(projectID, &ErrorPayload{Code: ErrorCodeInternal, Message: "no se pudo procesar el mensaje", Type: messageType})
+	}
+}
@@ -0,0 +1,69 @@
+package socket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+)
+
+// stubChat guarda los mensajes en memoria
+type stubChat struct {
+	services.ChatService
+	stored map[uuid.UUID]*entity.ChatMessage
+}
+
+func (s *stubChat) SendMessage(projectID string, userID uuid.UUID, username, text string) (*entity.ChatMessage, error) {
+	message := &entity.ChatMessage{ID: uuid.New(), UserID: userID, Username: username, Text: text}
+	s.stored[message.ID] = message
+	return message, nil
+}
+
+func (s *stubChat) DeleteMessage(projectID, messageID string, userID uuid.UUID) (*entity.ChatMessage, error) {
+	id := uuid.MustParse(messageID)
+	message, ok := s.stored[id]
+	if !ok || message.UserID != userID {
+		return nil, errors.New("mensaje no encontrado")
+	}
+	delete(s.stored, id)
+	return message, nil
+}
+
+// Un mensaje de chat que la sala no pudo recibir no queda en el historial
+func TestBroadcastToRoomDiscardsDroppedChat(t *testing.T) {
+	chat := &stubChat{stored: make(map[uuid.UUID]*entity.ChatMessage)}
+	room := &Room{
+		ID:        testProjectID,
+		Broadcast: make(chan Message, 1),
+		done:      make(chan struct{}),
+		hub:       &Hub{chat: chat},
+	}
+	send := func() error {
+		return room.BroadcastToRoom(Message{
+			Type:     MessageChat,
+			Data:     &ChatPayload{Text: "hola"},
+			UserID:   uuid.NewString(),
+			Username: "alice",
+		})
+	}
+
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(); !errors.Is(err, ErrRoomBusy) {
+		t.Fatalf("se esperaba ErrRoomBusy, se obtuvo %v", err)
+	}
+	if len(chat.stored) != 1 {
+		t.Fatalf("se esperaba solo el mensaje entregado en el historial, hay %d", len(chat.stored))
+	}
+
+	close(room.done)
+	if err := send(); !errors.Is(err, ErrRoomClosed) {
+		t.Fatalf("se esperaba un error de la sala cerrada, se obtuvo %v", err)
+	}
+	if len(chat.stored) != 1 {
+		t.Fatalf("el mensaje a la sala cerrada quedó en el historial")
+	}
+}
@@ -2,20 +2,23 @@ package socket
 
 import (
 	"bytes"
-	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 1024 * 8
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
 )
 
 var (
@@ -28,26 +31,49 @@ var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024 * 4,
 	WriteBufferSize: 1024 * 4,
 	CheckOrigin:     func(r *http.Request) bool { return true },
+	// permessage-deflate se negocia con los clientes que lo ofrecen
+	EnableCompression: true,
 }
 
 // Client es un intermediario entre la conexión websocket y el hub
 type Client struct {
 	hub       *Hub
 	conn      *websocket.Conn
-	send      chan []byte
+	send      chan *frame
+	ID        string // Identifica la conexión; un usuario puede tener varias
 	ProjectID string
 	UserID    string
 	Username  string
+	Role      string // Rol del usuario en el proyecto
+	Spectator bool   // Solo lectura; no ocupa un lugar de editor
+	encoding  string // EncodingJSON o EncodingMsgpack
+	limiter   *rateLimiter
+
+	resumeFrom *int64       // Último frame de la sala que el cliente recibió antes de reconectar
+	wantsQueue bool         // Esperar un lugar de editor si la sala está llena
+	admitted   atomic.Bool  // Ya entró a la sala; mientras espera solo puede enviar ping
+	mutedUntil atomic.Int64 // Silenciado hasta este instante (UnixNano); 0 si no
+
+	// Estado del envío; solo lo usa la goroutine de la sala
+	lagging       bool         // El canal de envío está casi lleno
+	presenceStale bool         // Se descartó awareness; se envía un snapshot al recuperarse
+	evicted       bool         // Marcado para desconectar
+	droppedFrames atomic.Int64 // Frames que no se le enviaron
+
+	// Código y motivo del cierre; se fijan antes de cerrar send
+	closeCode int
+	closeText string
 }
 
-// readPump (sin cambios)
+// readPump lee los frames del cliente y los despacha según el catálogo de mensajes
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
+	// Un frame más grande que todos los límites se cierra con 1009 (mensaje demasiado grande)
+	c.conn.SetReadLimit(c.limiter.limits.readLimit())
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -55,7 +81,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, messageBytes, err := c.conn.ReadMessage()
+		messageType, messageBytes, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error reading websocket message: %v", err)
@@ -63,65 +89,52 @@ func (c *Client) readPump() {
 			break
 		}
 
-		messageBytes = bytes.TrimSpace(bytes.Replace(messageBytes, newline, space, -1))
-
-		var incoming Message
-		if err := json.Unmarshal(messageBytes, &incoming); err != nil {
-			log.Printf("Error parsing message: %v", err)
-			continue
+		// Los clientes MessagePack envían frames binarios; el catálogo trabaja sobre JSON
+		if messageType == websocket.BinaryMessage && c.encoding == EncodingMsgpack {
+			if messageBytes, err = msgpackToJSON(messageBytes); err != nil {
+				messageBytes = nil
+			}
 		}
 
-		incoming.ProjectID = c.ProjectID
-		incoming.UserID = c.UserID
-		incoming.Username = c.Username
+		messageBytes = bytes.TrimSpace(bytes.Replace(messageBytes, newline, space, -1))
 
-		if room := c.hub.GetRoom(c.ProjectID); room != nil {
-			if err := room.BroadcastToRoom(incoming); err != nil {
-				log.Printf("Error broadcasting message: %v", err)
-			}
+		// Solo se aceptan los mensajes del catálogo; el resto recibe un frame de error
+		if !c.dispatch(messageBytes) {
+			// WriteControl se puede usar junto a writePump
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "demasiados mensajes"),
+				time.Now().Add(writeWait))
+			break
 		}
 	}
 }
 
-/* ---------- SOLUCIÓN: writePump sin concatenar JSON ---------- */
+// writePump escribe los frames de la sala en la conexión, uno por mensaje WebSocket
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.connections.Done()
 	}()
 
-	// helper para escribir un frame
-	write := func(msg []byte) error {
-		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-		return c.conn.WriteMessage(websocket.TextMessage, msg)
-	}
-
 	for {
 		select {
-		case msg, ok := <-c.send:
+		case f, ok := <-c.send:
 			if !ok {
 				// canal cerrado → cerrar WS
-				write(websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				code := websocket.CloseNormalClosure
+				if c.closeCode != 0 {
+					code = c.closeCode
+				}
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, c.closeText))
 				return
 			}
-			if err := write(msg); err != nil {
+			if err := c.writeFrame(f); err != nil {
 				return
 			}
 
-			// drenar backlog sin concatenar
-		DRAIN:
-			for {
-				select {
-				case next := <-c.send:
-					if err := write(next); err != nil {
-						return
-					}
-				default:
-					break DRAIN
-				}
-			}
-
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -131,32 +144,96 @@ func (c *Client) writePump() {
 	}
 }
 
-// WebSocketHandler (sin cambios)
-func WebSocketHandler(hub *Hub) gin.HandlerFunc {
+// writeFrame escribe el frame ya preparado en la codificación de la conexión. Los frames
+// chicos no se comprimen: deflate no les ahorra nada y cuesta CPU.
+func (c *Client) writeFrame(f *frame) error {
+	prepared, err := f.preparedFor(c.encoding)
+	if err != nil {
+		log.Printf("Error codificando frame para el cliente %s: %v", c.UserID, err)
+		return nil
+	}
+	c.conn.EnableWriteCompression(len(f.data) >= compressThreshold)
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WritePreparedMessage(prepared)
+}
+
+// WebSocketHandler autentica al usuario antes de hacer el upgrade; su identidad sale del JWT
+func WebSocketHandler(hub *Hub, auth *Authenticator, projects services.ProjectService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		projectID := c.Query("project_id")
-		userID := c.Query("user_id")
-		username := c.Query("username")
+		user, subprotocol, err := auth.Authenticate(c.Request)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+			return
+		}
 
-		if projectID == "" || userID == "" || username == "" {
+		projectID := c.Query("project_id")
+		if projectID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Missing required parameters: project_id, user_id, username",
+				"error": "Missing required parameter: project_id",
 			})
 			return
 		}
 
-		if room := hub.GetRoom(projectID); room != nil {
-			room.mutex.RLock()
-			full := len(room.Clients) >= room.MaxUsers
-			room.mutex.RUnlock()
-			if full {
-				c.JSON(http.StatusForbidden, gin.H{"error": "La sala está llena. Máximo 4 usuarios"})
+		// resume_from es el último "seq" recibido en una conexión anterior
+		var resumeFrom *int64
+		if value := c.Query("resume_from"); value != "" {
+			seq, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || seq < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "resume_from inválido"})
 				return
 			}
+			resumeFrom = &seq
 		}
 
-		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		// La sala solo se crea para proyectos existentes a los que el usuario tiene acceso
+		role, ok := projectAccess(c, projects, projectID, user.ID)
+		if !ok {
+			return
+		}
+
+		// Un usuario con ban no entra hasta que venza
+		ban, err := hub.sanction(projectID, user.ID, entity.ModerationBan)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las sanciones del usuario"})
+			return
+		}
+		if ban != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "Un moderador te expulsó de esta sala",
+				"reason":     ban.Reason,
+				"expires_at": ban.ExpiresAt,
+			})
+			return
+		}
+		mute, err := hub.sanction(projectID, user.ID, entity.ModerationMute)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las sanciones del usuario"})
+			return
+		}
+
+		encoding := c.DefaultQuery("encoding", EncodingJSON)
+		if encoding != EncodingJSON && encoding != EncodingMsgpack {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "encoding debe ser json o msgpack"})
+			return
+		}
+
+		// Los viewers siempre entran como espectadores; el resto puede elegirlo con spectate=true.
+		// Si la sala está llena se decide en la sala, después del upgrade, con un frame room_full.
+		spectator := role == entity.ProjectRoleViewer || c.Query("spectate") == "true"
+
+		// Si el token llegó como subprotocolo el servidor debe aceptarlo en la respuesta
+		var header http.Header
+		if subprotocol != "" {
+			header = http.Header{"Sec-WebSocket-Protocol": {subprotocol}}
+		}
+
+		if !hub.acceptConnection() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "El servidor se está reiniciando"})
+			return
+		}
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, header)
 		if err != nil {
+			hub.connections.Done()
 			log.Printf("Failed to upgrade connection: %v", err)
 			return
 		}
@@ -164,10 +241,22 @@ func WebSocketHandler(hub *Hub) gin.HandlerFunc {
 		client := &Client{
 			hub:       hub,
 			conn:      conn,
-			send:      make(chan []byte, 512),
+			send:      make(chan *frame, 512),
+			ID:        uuid.NewString(),
 			ProjectID: projectID,
-			UserID:    userID,
-			Username:  username,
+			UserID:    user.ID.String(),
+			Username:  displayName(user),
+			Role:      role,
+			Spectator: spectator,
+			encoding:  encoding,
+			limiter:   newRateLimiter(hub.limits),
+
+			resumeFrom: resumeFrom,
+			wantsQueue: c.Query("wait") == "true",
+		}
+
+		if mute != nil {
+			client.mute(mute.ExpiresAt)
 		}
 
 		client.hub.register <- client
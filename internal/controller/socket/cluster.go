@@ -0,0 +1,449 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/backplane"
+	"github.com/google/uuid"
+)
+
+const (
+	// publishTimeout limita cuánto puede esperar la sala al backplane
+	publishTimeout = 5 * time.Second
+	// syncTimeout es cuánto espera una sala nueva el documento de otra instancia antes de
+	// quedarse con el de la base
+	syncTimeout = 2 * time.Second
+	// remoteNodeTTL es el tiempo sin noticias tras el cual se olvida la presencia de otra instancia
+	remoteNodeTTL = 3 * awarenessSweepPeriod
+)
+
+// Tipos de sobre que intercambian las salas de un proyecto entre instancias
+const (
+	kindFrame       = "frame"        // Frame para los clientes de la sala
+	kindPresence    = "presence"     // Cambios de awareness de los clientes de una instancia
+	kindOp          = "op"           // Operación de edición; todas las instancias la aplican en el orden del backplane
+	kindSyncRequest = "sync_request" // Una sala nueva pide el documento en vivo
+	kindSync        = "sync"         // Respuesta con el documento en vivo
+	kindModeration  = "moderation"   // Acción de moderación sobre las conexiones de un usuario
+	kindProject     = "project"      // Cambio del proyecto hecho por la API REST
+	kindDirect      = "direct"       // Mensaje para las conexiones de algunos usuarios
+	kindSaved       = "saved"        // La instancia que guarda el documento lo escribió en la base
+	kindClosed      = "closed"       // La sala de una instancia se cerró
+)
+
+// presenceUpdate es el contenido de un sobre de presencia
+type presenceUpdate struct {
+	Snapshot bool       `json:"snapshot,omitempty"` // Estados completos de la instancia; reemplaza los anteriores
+	States   []Presence `json:"states"`
+	Removed  []string   `json:"removed"`
+}
+
+// savedDocument es el contenido de un sobre kindSaved
+type savedDocument struct {
+	Seq      int64 `json:"seq"` // Última operación incluida en lo guardado
+	Revision int   `json:"revision"`
+}
+
+type syncRequest struct {
+	ID string `json:"id"`
+}
+
+// syncReply lleva además el log y los historiales, para que la sala nueva rebase y deshaga
+// igual que las demás
+type syncReply struct {
+	ID       string                  `json:"id"`
+	Seq      int64                   `json:"seq"`
+	Revision int                     `json:"revision"`
+	SavedSeq int64                   `json:"saved_seq"`
+	Content  map[string]interface{}  `json:"content"`
+	Log      []appliedOp             `json:"log"`
+	History  map[string]*userHistory `json:"history"`
+}
+
+// clusterState es lo que la sala sabe de las demás instancias. La sincronización solo la usa
+// run; la presencia remota se protege con r.mutex.
+type clusterState struct {
+	syncID      string     // Pedido de documento en curso; vacío si la sala ya está sincronizada
+	syncStarted bool       // Ya pasó el propio pedido por el backplane
+	buffered    []clientOp // Operaciones posteriores al pedido, a aplicar sobre la respuesta
+	recovering  bool       // El pedido es por sobres perdidos, no por abrir la sala
+
+	remote map[string]*remoteNode
+}
+
+// remoteNode son los clientes de otra instancia conectados a la misma sala
+type remoteNode struct {
+	states map[string]Presence
+	seenAt time.Time
+}
+
+// receive entrega a la sala local los sobres publicados por las instancias. No espera a una
+// sala atrasada, porque todas comparten la goroutine del backplane: si su cola está llena el
+// sobre se descarta y la sala se resincroniza.
+func (h *Hub) receive(envelope backplane.Envelope) {
+	if envelope.Kind == backplane.KindGap {
+		h.mutex.RLock()
+		for _, room := range h.rooms {
+			room.signalGap()
+		}
+		h.mutex.RUnlock()
+		return
+	}
+
+	room := h.GetRoom(envelope.ProjectID)
+	if room == nil {
+		return
+	}
+	select {
+	case room.remote <- envelope:
+	default:
+		log.Printf("Sala %s atrasada: se descarta el sobre %s y se resincroniza", room.ID, envelope.Kind)
+		room.signalGap()
+	}
+}
+
+// signalGap avisa a run que se perdieron sobres, sin bloquear
+func (r *Room) signalGap() {
+	select {
+	case r.gap <- struct{}{}:
+	default:
+	}
+}
+
+// publish envía un sobre a las salas del mismo proyecto en las demás instancias
+func (r *Room) publish(kind string, data interface{}) {
+	if r.hub == nil {
+		return
+	}
+	r.hub.publish(r.ID, kind, data)
+}
+
+// publish envía un sobre a las salas del proyecto en las demás instancias, aunque en esta
+// no haya una abierta
+func (h *Hub) publish(projectID, kind string, data interface{}) {
+	if h.backplane == nil {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error serializando el sobre %s de la sala %s: %v", kind, projectID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	err = h.backplane.Publish(ctx, backplane.Envelope{
+		Node:      h.node,
+		ProjectID: projectID,
+		Kind:      kind,
+		Data:      raw,
+	})
+	if err != nil {
+		log.Printf("Error publicando %s de la sala %s: %v", kind, projectID, err)
+	}
+}
+
+// writer indica si esta instancia es la que guarda el documento. Con varias instancias lo
+// hace solo la de menor node entre las que tienen la sala abierta, así el guardado de una
+// no pisa el de otra; las demás lo retoman si esa sala se cierra o deja de dar noticias.
+func (r *Room) writer() bool {
+	if r.hub == nil || r.hub.backplane == nil {
+		return true
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for node := range r.cluster.remote {
+		if node < r.hub.node {
+			return false
+		}
+	}
+	return true
+}
+
+// requestSync pide el documento en vivo a las demás instancias. Hasta que llega la respuesta
+// o vence syncTimeout la sala no acepta operaciones propias.
+func (r *Room) requestSync() <-chan time.Time {
+	r.cluster.syncID = uuid.NewString()
+	r.publish(kindSyncRequest, syncRequest{ID: r.cluster.syncID})
+	return time.After(syncTimeout)
+}
+
+// resync vuelve a pedir el documento en vivo cuando se perdieron sobres del backplane. La
+// respuesta reemplaza lo aplicado hasta el pedido; si nadie responde queda el documento propio.
+func (r *Room) resync() <-chan time.Time {
+	log.Printf("Sala %s: se perdieron sobres del backplane, se pide el documento en vivo", r.ID)
+	r.cluster.syncStarted = false
+	r.cluster.buffered = nil
+	r.cluster.recovering = true
+	return r.requestSync()
+}
+
+func (r *Room) syncing() bool {
+	return r.cluster.syncID != ""
+}
+
+// finishSync adopta el documento recibido, si lo hay, y aplica las operaciones que llegaron
+// después del pedido. Retorna si el documento cambió.
+func (r *Room) finishSync(reply *syncReply) bool {
+	changed := false
+	if reply != nil {
+		r.document.content = reply.Content
+		r.document.seq = reply.Seq
+		r.document.revision = reply.Revision
+		r.document.savedSeq = reply.SavedSeq
+		r.document.log = reply.Log
+		r.document.history = reply.History
+		r.document.loaded = true
+	}
+	if reply != nil || r.cluster.recovering {
+		// Los clientes que entraron durante la sincronización recibieron el documento de la
+		// base, y tras perder sobres pudo perderse el eco de sus operaciones
+		r.mutex.RLock()
+		for client := range r.Clients {
+			r.sendDocument(client)
+		}
+		r.mutex.RUnlock()
+	}
+
+	buffered := r.cluster.buffered
+	// La presencia remota se lee también desde los readPump
+	r.mutex.Lock()
+	r.cluster = clusterState{remote: r.cluster.remote}
+	r.mutex.Unlock()
+	for _, op := range buffered {
+		if r.applyOp(op) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// handleRemote procesa un sobre del backplane. Retorna si el documento cambió.
+func (r *Room) handleRemote(envelope backplane.Envelope) bool {
+	own := envelope.Node == r.hub.node
+
+	switch envelope.Kind {
+	case kindOp:
+		var op clientOp
+		if err := json.Unmarshal(envelope.Data, &op); err != nil {
+			log.Printf("Operación remota inválida en la sala %s: %v", r.ID, err)
+			return false
+		}
+		if own {
+			op.client = r.clientByID(op.ClientID)
+		}
+		if r.syncing() {
+			// Las anteriores al pedido vienen incluidas en la respuesta
+			if r.cluster.syncStarted {
+				r.cluster.buffered = append(r.cluster.buffered, op)
+			}
+			return false
+		}
+		return r.applyOp(op)
+
+	case kindSyncRequest:
+		var request syncRequest
+		if err := json.Unmarshal(envelope.Data, &request); err != nil {
+			return false
+		}
+		if own {
+			if request.ID == r.cluster.syncID {
+				r.cluster.syncStarted = true
+			}
+			return false
+		}
+		// Solo responde una sala con el documento al día
+		if r.syncing() || !r.document.loaded {
+			return false
+		}
+		r.publish(kindSync, syncReply{
+			ID:       request.ID,
+			Seq:      r.document.seq,
+			Revision: r.document.revision,
+			SavedSeq: r.document.savedSeq,
+			Content:  r.document.content,
+			Log:      r.document.log,
+			History:  r.document.history,
+		})
+
+	case kindSync:
+		var reply syncReply
+		if err := json.Unmarshal(envelope.Data, &reply); err != nil {
+			return false
+		}
+		if own || !r.syncing() || reply.ID != r.cluster.syncID || reply.Content == nil {
+			return false
+		}
+		return r.finishSync(&reply)
+
+	case kindFrame:
+		if own {
+			return false
+		}
+		var message Message
+		if err := json.Unmarshal(envelope.Data, &message); err != nil {
+			log.Printf("Frame remoto inválido en la sala %s: %v", r.ID, err)
+			return false
+		}
+		r.broadcastLocal(message)
+
+	case kindPresence:
+		if own {
+			return false
+		}
+		var update presenceUpdate
+		if err := json.Unmarshal(envelope.Data, &update); err != nil {
+			return false
+		}
+		r.mergeRemotePresence(envelope.Node, update)
+
+	case kindModeration:
+		if own {
+			return false
+		}
+		var action moderationAction
+		if err := json.Unmarshal(envelope.Data, &action); err != nil {
+			return false
+		}
+		r.applyModeration(r.hub, action)
+
+	case kindDirect:
+		if own {
+			return false
+		}
+		var addressed addressedMessage
+		if err := json.Unmarshal(envelope.Data, &addressed); err != nil {
+			return false
+		}
+		r.sendToUsers(addressed.UserIDs, addressed.Message)
+
+	case kindProject:
+		if own {
+			return false
+		}
+		var change projectChange
+		if err := json.Unmarshal(envelope.Data, &change); err != nil {
+			return false
+		}
+		r.applyProjectChange(r.hub, change)
+
+	case kindSaved:
+		if own {
+			return false
+		}
+		var saved savedDocument
+		if err := json.Unmarshal(envelope.Data, &saved); err != nil {
+			return false
+		}
+		r.document.revision = max(r.document.revision, saved.Revision)
+		r.document.savedSeq = max(r.document.savedSeq, saved.Seq)
+		if saved.Seq >= r.document.seq {
+			r.document.dirty = false
+		}
+
+	case kindClosed:
+		if own {
+			return false
+		}
+		r.forgetNode(envelope.Node)
+		// Si la que guardaba era esa instancia, ahora puede tocarle a esta
+		return r.document.dirty && r.writer()
+	}
+	return false
+}
+
+// forgetNode olvida la presencia de una instancia cuya sala se cerró
+func (r *Room) forgetNode(node string) {
+	r.mutex.Lock()
+	var removed []string
+	if remote, ok := r.cluster.remote[node]; ok {
+		for id := range remote.states {
+			removed = append(removed, id)
+		}
+		delete(r.cluster.remote, node)
+	}
+	r.mutex.Unlock()
+
+	r.broadcastAwareness(nil, removed)
+}
+
+// mergeRemotePresence aplica los estados de otra instancia y avisa a los clientes locales
+func (r *Room) mergeRemotePresence(node string, update presenceUpdate) {
+	r.mutex.Lock()
+	remote, ok := r.cluster.remote[node]
+	if !ok {
+		remote = &remoteNode{states: make(map[string]Presence)}
+		r.cluster.remote[node] = remote
+	}
+	remote.seenAt = time.Now()
+
+	states := update.States
+	removed := append([]string{}, update.Removed...)
+	if update.Snapshot {
+		// El latido trae todos los estados: se avisa solo lo que no se conocía y lo que ya no está
+		seen := make(map[string]bool, len(update.States))
+		states = nil
+		for _, state := range update.States {
+			seen[state.ClientID] = true
+			if _, known := remote.states[state.ClientID]; !known {
+				states = append(states, state)
+			}
+		}
+		for id := range remote.states {
+			if !seen[id] {
+				removed = append(removed, id)
+			}
+		}
+		remote.states = make(map[string]Presence, len(update.States))
+	}
+	for _, state := range update.States {
+		remote.states[state.ClientID] = state
+	}
+	for _, id := range update.Removed {
+		delete(remote.states, id)
+	}
+	r.mutex.Unlock()
+
+	r.broadcastAwareness(states, removed)
+}
+
+// heartbeat publica los estados locales completos y olvida las instancias que dejaron de hacerlo
+func (r *Room) heartbeat() {
+	if r.hub.backplane == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	states := make([]Presence, 0, len(r.presence))
+	for _, p := range r.presence {
+		states = append(states, copyPresence(p))
+	}
+	var removed []string
+	for node, remote := range r.cluster.remote {
+		if time.Since(remote.seenAt) > remoteNodeTTL {
+			for id := range remote.states {
+				removed = append(removed, id)
+			}
+			delete(r.cluster.remote, node)
+		}
+	}
+	r.mutex.Unlock()
+
+	r.publish(kindPresence, presenceUpdate{Snapshot: true, States: states, Removed: []string{}})
+	r.broadcastAwareness(nil, removed)
+}
+
+// clientByID busca una conexión local por su ID
+func (r *Room) clientByID(id string) *Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for client := range r.Clients {
+		if client.ID == id {
+			return client
+		}
+	}
+	return nil
+}
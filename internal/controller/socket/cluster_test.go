@@ -0,0 +1,399 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/backplane"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+const testProjectID = "project"
+
+// projectStore es la base compartida por los hubs de una prueba
+type projectStore struct {
+	mutex    sync.Mutex
+	content  datatypes.JSON
+	revision int
+	saves    map[string]int // Guardados hechos por cada hub
+}
+
+func newProjectStore(content string) *projectStore {
+	return &projectStore{content: datatypes.JSON(content), revision: 1, saves: make(map[string]int)}
+}
+
+// stubProjects es el ProjectService de un hub de prueba; solo carga y guarda el Content
+type stubProjects struct {
+	services.ProjectService
+	name  string
+	store *projectStore
+}
+
+func (s *stubProjects) GetProjectByID(id string) (*entity.Project, error) {
+	s.store.mutex.Lock()
+	defer s.store.mutex.Unlock()
+	return &entity.Project{Content: s.store.content, Revision: s.store.revision}, nil
+}
+
+func (s *stubProjects) SaveRoomContent(id string, content datatypes.JSON, revision int) (*entity.Project, error) {
+	s.store.mutex.Lock()
+	defer s.store.mutex.Unlock()
+	if revision != s.store.revision {
+		return nil, repositories.ErrRevisionConflict
+	}
+	s.store.content = content
+	s.store.revision++
+	s.store.saves[s.name]++
+	return &entity.Project{Content: content, Revision: s.store.revision}, nil
+}
+
+// write simula un cambio del Content hecho por la API REST
+func (s *projectStore) write(content string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.content = datatypes.JSON(content)
+	s.revision++
+}
+
+// touch simula un cambio por la API REST que no toca el Content, como el título
+func (s *projectStore) touch() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.revision++
+}
+
+// newTestHub crea un hub con node fijo, así la prueba sabe cuál guarda el documento
+func newTestHub(node string, store *projectStore, bp backplane.Backplane) *Hub {
+	hub := NewHub(Dependencies{
+		Events:    event.NewBus(),
+		Projects:  &stubProjects{name: node, store: store},
+		Backplane: bp,
+	})
+	hub.node = node
+	go hub.Run()
+	return hub
+}
+
+// receivedMessage es un frame recibido por un cliente de prueba
+type receivedMessage struct {
+	Type   string          `json:"type"`
+	Seq    int64           `json:"seq"`
+	Data   json.RawMessage `json:"data"`
+	UserID string          `json:"user_id"`
+}
+
+// testClient es una conexión sin WebSocket: guarda los frames que la sala le envía
+type testClient struct {
+	*Client
+	mutex    sync.Mutex
+	messages []receivedMessage
+	next     int           // Primer mensaje que waitFor todavía no revisó
+	closed   chan struct{} // Se cierra cuando la sala cierra el canal de envío
+}
+
+// connectTestClient conecta un cliente editor; configure ajusta la conexión antes de registrarla
+func connectTestClient(hub *Hub, userID string, configure ...func(*Client)) *testClient {
+	c := &testClient{Client: &Client{
+		hub:       hub,
+		send:      make(chan *frame, 512),
+		ID:        uuid.NewString(),
+		ProjectID: testProjectID,
+		UserID:    userID,
+		Username:  userID,
+		Role:      entity.ProjectRoleOwner,
+		encoding:  EncodingJSON,
+		limiter:   newRateLimiter(hub.limits),
+	}, closed: make(chan struct{})}
+	for _, f := range configure {
+		f(c.Client)
+	}
+	go func() {
+		defer close(c.closed)
+		for f := range c.send {
+			var message receivedMessage
+			if err := json.Unmarshal(f.data, &message); err == nil {
+				c.mutex.Lock()
+				c.messages = append(c.messages, message)
+				c.mutex.Unlock()
+			}
+		}
+	}()
+	hub.register <- c.Client
+	return c
+}
+
+// waitFor espera el siguiente mensaje que cumpla match
+func (c *testClient) waitFor(t *testing.T, description string, match func(receivedMessage) bool) receivedMessage {
+	t.Helper()
+	message, ok := c.await(match)
+	if !ok {
+		t.Fatalf("%s no recibió %s", c.UserID, description)
+	}
+	return message
+}
+
+// await es waitFor para las goroutines de una prueba: retorna si el mensaje llegó a tiempo
+func (c *testClient) await(match func(receivedMessage) bool) (receivedMessage, bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mutex.Lock()
+		for ; c.next < len(c.messages); c.next++ {
+			if message := c.messages[c.next]; match(message) {
+				c.next++
+				c.mutex.Unlock()
+				return message, true
+			}
+		}
+		c.mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	return receivedMessage{}, false
+}
+
+func ofType(messageType string) func(receivedMessage) bool {
+	return func(m receivedMessage) bool { return m.Type == messageType }
+}
+
+// sees indica si un mensaje de awareness trae el estado de la conexión
+func sees(clientID string) func(receivedMessage) bool {
+	return func(m receivedMessage) bool {
+		if m.Type != MessageAwareness {
+			return false
+		}
+		var data struct {
+			States []Presence `json:"states"`
+		}
+		json.Unmarshal(m.Data, &data)
+		for _, state := range data.States {
+			if state.ClientID == clientID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type opData struct {
+	Seq int64             `json:"seq"`
+	Op  content.Operation `json:"op"`
+}
+
+// Dos instancias sobre el mismo backplane aplican las operaciones en el mismo orden, se ven
+// en la presencia, una tercera se sincroniza con el documento en vivo y solo una guarda
+func TestHubsConvergeOverBackplane(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	a := newTestHub("node-a", store, bp)
+	b := newTestHub("node-b", store, bp)
+
+	alice := connectTestClient(b, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+	bob := connectTestClient(a, "bob")
+	bob.waitFor(t, "el documento", ofType(MessageDocument))
+
+	alice.waitFor(t, "la presencia de bob", sees(bob.ID))
+	bob.waitFor(t, "la presencia de alice", sees(alice.ID))
+
+	const opsPerClient = 10
+	base := int64(0)
+	for i := 0; i < opsPerClient; i++ {
+		for _, c := range []*testClient{alice, bob} {
+			c.hub.GetRoom(testProjectID).SubmitOp(c.Client, editRequest{
+				BaseSeq: &base,
+				Operation: content.Operation{
+					Type:     content.OpInsertNode,
+					NodeID:   fmt.Sprintf("%s-%d", c.UserID, i),
+					ParentID: content.RootID,
+					Index:    i,
+					Node:     map[string]interface{}{content.TypeKey: "Text"},
+				},
+			})
+		}
+	}
+
+	// Cada cliente aplica las operaciones en el orden recibido sobre su copia
+	received := func(c *testClient) ([]opData, map[string]interface{}) {
+		doc := map[string]interface{}{content.IDKey: "root", content.ChildrenKey: []interface{}{}}
+		ops := make([]opData, 0, 2*opsPerClient)
+		for len(ops) < 2*opsPerClient {
+			var data opData
+			json.Unmarshal(c.waitFor(t, "las operaciones", ofType(MessageOp)).Data, &data)
+			if _, err := content.Apply(doc, data.Op); err != nil {
+				t.Fatalf("%s no pudo aplicar la operación %d: %v", c.UserID, data.Seq, err)
+			}
+			ops = append(ops, data)
+		}
+		return ops, doc
+	}
+	aliceOps, aliceDoc := received(alice)
+	bobOps, bobDoc := received(bob)
+	if !reflect.DeepEqual(aliceOps, bobOps) {
+		t.Fatalf("las instancias aplicaron distintas operaciones:\nalice %v\nbob   %v", aliceOps, bobOps)
+	}
+	if !reflect.DeepEqual(aliceDoc, bobDoc) {
+		t.Fatalf("los documentos no convergen:\nalice %v\nbob   %v", aliceDoc, bobDoc)
+	}
+
+	// Una instancia nueva recibe el documento en vivo, no el de la base
+	c := newTestHub("node-c", store, bp)
+	carol := connectTestClient(c, "carol")
+	var synced struct {
+		Seq     int64                  `json:"seq"`
+		Content map[string]interface{} `json:"content"`
+	}
+	for synced.Seq != 2*opsPerClient {
+		json.Unmarshal(carol.waitFor(t, "el documento en vivo", ofType(MessageDocument)).Data, &synced)
+	}
+	if !reflect.DeepEqual(synced.Content, aliceDoc) {
+		t.Fatalf("la sincronización no trajo el documento en vivo:\ncarol %v\nalice %v", synced.Content, aliceDoc)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, hub := range []*Hub{b, c, a} {
+		if err := hub.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if len(store.saves) != 1 || store.saves["node-a"] == 0 {
+		t.Fatalf("solo node-a debía guardar el documento, guardaron %v", store.saves)
+	}
+	saved, _ := content.Parse(store.content)
+	if !reflect.DeepEqual(saved, aliceDoc) {
+		t.Fatalf("se guardó otro documento:\nbase  %v\nalice %v", saved, aliceDoc)
+	}
+}
+
+// Cada instancia numera sus frames por su cuenta: un seq recibido en otra instancia no se
+// puede reanudar y pide resync
+func TestResumeFromAnotherInstanceRequiresResync(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	a := newTestHub("node-a", store, bp)
+	b := newTestHub("node-b", store, bp)
+
+	alice := connectTestClient(a, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+	a.GetRoom(testProjectID).SubmitOp(alice.Client, insertRequest(0, "a"))
+	seq := alice.waitFor(t, "la operación", ofType(MessageOp)).Seq
+
+	resumeFrom := func(seq int64) func(*Client) {
+		return func(c *Client) { c.resumeFrom = &seq }
+	}
+	same := connectTestClient(a, "alice", resumeFrom(seq-1))
+	same.waitFor(t, "la operación repetida", func(m receivedMessage) bool {
+		return m.Type == MessageOp && m.Seq == seq
+	})
+	other := connectTestClient(b, "alice", resumeFrom(seq))
+	other.waitFor(t, "el resync", ofType(MessageResyncRequired))
+
+	same.mutex.Lock()
+	defer same.mutex.Unlock()
+	for _, m := range same.messages {
+		if m.Type == MessageResyncRequired {
+			t.Fatal("la misma instancia debía reanudar sin resync")
+		}
+	}
+}
+
+// Dos salas abiertas a la vez no comparten números aunque numeren la misma cantidad de frames
+func TestReplayBuffersDoNotOverlap(t *testing.T) {
+	a, b := newReplayBuffer(), newReplayBuffer()
+	seq := a.next()
+	a.record(seq, &frame{})
+	b.record(b.next(), &frame{})
+
+	if frames, ok := a.since(seq - 1); !ok || len(frames) != 1 {
+		t.Fatalf("la misma sala debía repetir su frame, se obtuvo %d (%v)", len(frames), ok)
+	}
+	if frames, ok := b.since(seq - 1); ok {
+		t.Fatalf("otra sala repitió %d frames con un seq ajeno", len(frames))
+	}
+}
+
+// lossyBackplane deja de entregar a su hub los sobres mientras drop está activo, como una
+// instancia que perdió la conexión con el backplane
+type lossyBackplane struct {
+	backplane.Backplane
+	drop atomic.Bool
+}
+
+func (b *lossyBackplane) Subscribe(handler backplane.Handler) {
+	b.Backplane.Subscribe(func(envelope backplane.Envelope) {
+		if !b.drop.Load() {
+			handler(envelope)
+		}
+	})
+}
+
+// Tras perder sobres del backplane la sala pide el documento en vivo a las demás instancias
+func TestRoomResyncsAfterBackplaneGap(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	lossy := &lossyBackplane{Backplane: bp}
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	a := newTestHub("node-a", store, bp)
+	b := newTestHub("node-b", store, lossy)
+
+	alice := connectTestClient(a, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+	bob := connectTestClient(b, "bob")
+	bob.waitFor(t, "el documento", ofType(MessageDocument))
+	alice.waitFor(t, "la presencia de bob", sees(bob.ID))
+
+	lossy.drop.Store(true)
+	a.GetRoom(testProjectID).SubmitOp(alice.Client, insertRequest(0, "x"))
+	alice.waitFor(t, "la operación", ofType(MessageOp))
+	lossy.drop.Store(false)
+
+	b.receive(backplane.Envelope{Kind: backplane.KindGap})
+	var doc documentData
+	for len(doc.Content) == 0 || len(childOrder(doc.Content)) == 0 {
+		json.Unmarshal(bob.waitFor(t, "el documento resincronizado", ofType(MessageDocument)).Data, &doc)
+	}
+	if got := childOrder(doc.Content); !reflect.DeepEqual(got, []string{"x"}) || doc.Seq != 1 {
+		t.Fatalf("se esperaba [x] en la operación 1, se obtuvo %v en %d", got, doc.Seq)
+	}
+}
+
+// Una sala que no vacía su cola no bloquea la entrega a las demás: pierde el sobre y se resincroniza
+func TestReceiveDoesNotBlockOnFullRoom(t *testing.T) {
+	hub := newTestHub("node", newProjectStore(`{"id":"root","children":[]}`), nil)
+	stuck := &Room{ID: "stuck", remote: make(chan backplane.Envelope), gap: make(chan struct{}, 1)}
+	hub.mutex.Lock()
+	hub.rooms[stuck.ID] = stuck
+	hub.mutex.Unlock()
+
+	delivered := make(chan struct{})
+	go func() {
+		hub.receive(backplane.Envelope{ProjectID: stuck.ID, Kind: kindOp})
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("receive se bloqueó esperando a la sala")
+	}
+	select {
+	case <-stuck.gap:
+	default:
+		t.Fatal("la sala no quedó marcada para resincronizarse")
+	}
+}
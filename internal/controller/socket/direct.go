@@ -0,0 +1,179 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Tipos de mensaje entre colaboradores, siempre dirigidos con "to"
+const (
+	MessageRPCRequest  = "rpc_request"  // Pedido a otros usuarios, por ejemplo "follow"
+	MessageRPCResponse = "rpc_response" // Respuesta a un rpc_request, con su mismo id
+)
+
+const (
+	// maxRecipients es cuántos usuarios puede tener "to"
+	maxRecipients = 20
+	// maxRPCNameBytes limita el id y el método de los mensajes rpc
+	maxRPCNameBytes = 64
+	// maxRPCErrorBytes limita el error de un rpc_response
+	maxRPCErrorBytes = 512
+)
+
+type RPCRequestPayload struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+func (p *RPCRequestPayload) Validate() error {
+	if p.ID == "" || len(p.ID) > maxRPCNameBytes {
+		return fmt.Errorf("id es requerido y no puede superar los %d bytes", maxRPCNameBytes)
+	}
+	if p.Method == "" || len(p.Method) > maxRPCNameBytes {
+		return fmt.Errorf("method es requerido y no puede superar los %d bytes", maxRPCNameBytes)
+	}
+	return nil
+}
+
+type RPCResponsePayload struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func (p *RPCResponsePayload) Validate() error {
+	if p.ID == "" || len(p.ID) > maxRPCNameBytes {
+		return fmt.Errorf("id es requerido y no puede superar los %d bytes", maxRPCNameBytes)
+	}
+	if len(p.Error) > maxRPCErrorBytes {
+		return errors.New("error es demasiado largo")
+	}
+	return nil
+}
+
+// addressedMessage es un mensaje para las conexiones de algunos usuarios de la sala
+type addressedMessage struct {
+	UserIDs []string `json:"user_ids"`
+	Message Message  `json:"message"`
+}
+
+// SendToUsers envía un mensaje solo a las conexiones de los usuarios indicados, en esta
+// instancia y en las demás. Pasa por la goroutine de la sala, como Reply.
+func (r *Room) SendToUsers(message Message, userIDs ...string) {
+	select {
+	case r.direct <- directMessage{userIDs: userIDs, message: message}:
+	case <-r.done:
+		return
+	}
+	r.publish(kindDirect, addressedMessage{UserIDs: userIDs, Message: message})
+}
+
+// sendToUsers envía el mensaje a las conexiones admitidas de los usuarios en esta instancia.
+// Solo se usa desde run.
+func (r *Room) sendToUsers(userIDs []string, message Message) {
+	frame, err := newFrame(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+	recipients := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		recipients[id] = true
+	}
+
+	r.mutex.RLock()
+	var targets []*Client
+	for client := range r.Clients {
+		if recipients[client.UserID] {
+			targets = append(targets, client)
+		}
+	}
+	r.mutex.RUnlock()
+
+	for _, client := range targets {
+		r.sendFrame(client, frame, framePriority(message.Type))
+	}
+}
+
+// checkRecipients valida el campo "to" de un mensaje: solo lo admiten los tipos con handler
+// dirigido, los rpc lo exigen, y cada destinatario debe estar conectado a la sala
+func (r *Room) checkRecipients(spec messageSpec, incoming *incomingMessage) *ErrorPayload {
+	invalid := func(message string) *ErrorPayload {
+		return &ErrorPayload{Code: ErrorCodeInvalid, Message: message, Type: incoming.Type}
+	}
+	switch {
+	case len(incoming.To) == 0 && spec.handle == nil:
+		return invalid("to es requerido")
+	case len(incoming.To) == 0:
+		return nil
+	case spec.direct == nil:
+		return invalid("este tipo de mensaje no admite to")
+	case len(incoming.To) > maxRecipients:
+		return invalid(fmt.Sprintf("to no puede tener más de %d usuarios", maxRecipients))
+	}
+
+	seen := make(map[string]bool, len(incoming.To))
+	recipients := incoming.To[:0]
+	for _, id := range incoming.To {
+		if seen[id] {
+			continue
+		}
+		if !r.hasUser(id) {
+			return &ErrorPayload{Code: ErrorCodeNotFound, Message: "el destinatario no está conectado a la sala", Type: incoming.Type}
+		}
+		seen[id] = true
+		recipients = append(recipients, id)
+	}
+	incoming.To = recipients
+	return nil
+}
+
+// hasUser indica si el usuario tiene alguna conexión admitida en la sala, en cualquier instancia
+func (r *Room) hasUser(userID string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, p := range r.presence {
+		if p.UserID == userID {
+			return true
+		}
+	}
+	for _, remote := range r.cluster.remote {
+		for _, p := range remote.states {
+			if p.UserID == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// privateChat envía un mensaje de chat solo a los destinatarios y a las demás conexiones del
+// remitente. No se guarda en el historial del proyecto.
+func privateChat(room *Room, c *Client, p payload, to []string) {
+	room.SendToUsers(Message{
+		Type:      MessageChat,
+		To:        to,
+		Data:      p,
+		ProjectID: room.ID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+	}, append([]string{c.UserID}, to...)...)
+}
+
+// relayToUsers retransmite el payload validado a los destinatarios con la identidad del remitente
+func relayToUsers(messageType string) func(room *Room, c *Client, p payload, to []string) {
+	return func(room *Room, c *Client, p payload, to []string) {
+		room.SendToUsers(Message{
+			Type:      messageType,
+			To:        to,
+			Data:      p,
+			ProjectID: room.ID,
+			UserID:    c.UserID,
+			Username:  c.Username,
+		}, to...)
+	}
+}
@@ -0,0 +1,54 @@
+package socket
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// errorCode retorna el matcher del frame de error con ese código para el tipo rechazado
+func errorCode(code, messageType string) func(receivedMessage) bool {
+	return func(m receivedMessage) bool {
+		if m.Type != MessageError {
+			return false
+		}
+		var data ErrorPayload
+		json.Unmarshal(m.Data, &data)
+		return data.Code == code && data.Type == messageType
+	}
+}
+
+// Los mensajes dirigidos pasan por los mismos controles de rol y silencio que los de la sala
+func TestDirectMessagesRespectRoleAndMute(t *testing.T) {
+	hub := newTestHub("node", newProjectStore(`{"id":"root","children":[]}`), nil)
+	alice := connectTestClient(hub, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+	bob := connectTestClient(hub, "bob", func(c *Client) { c.Spectator = true })
+	bob.waitFor(t, "el documento", ofType(MessageDocument))
+	carol := connectTestClient(hub, "carol", func(c *Client) { c.mutedUntil.Store(math.MaxInt64) })
+	carol.waitFor(t, "el documento", ofType(MessageDocument))
+
+	const rpc = `{"type":"rpc_request","to":["alice"],"data":{"id":"1","method":"follow"}}`
+	bob.dispatch([]byte(rpc))
+	bob.waitFor(t, "el rechazo por espectador", errorCode(ErrorCodeForbidden, MessageRPCRequest))
+	carol.dispatch([]byte(rpc))
+	carol.waitFor(t, "el rechazo por silencio", errorCode(ErrorCodeMuted, MessageRPCRequest))
+	carol.dispatch([]byte(`{"type":"chat","to":["alice"],"data":{"text":"hola"}}`))
+	carol.waitFor(t, "el rechazo del chat privado", errorCode(ErrorCodeMuted, MessageChat))
+
+	// Un espectador puede chatear en privado, y un editor enviar rpc
+	bob.dispatch([]byte(`{"type":"chat","to":["alice"],"data":{"text":"hola"}}`))
+	alice.waitFor(t, "el chat privado de bob", func(m receivedMessage) bool {
+		return m.Type == MessageChat && m.UserID == "bob"
+	})
+	alice.dispatch([]byte(`{"type":"rpc_request","to":["bob"],"data":{"id":"2","method":"follow"}}`))
+	bob.waitFor(t, "el rpc de alice", ofType(MessageRPCRequest))
+
+	alice.mutex.Lock()
+	defer alice.mutex.Unlock()
+	for _, m := range alice.messages {
+		if m.Type == MessageRPCRequest || (m.Type == MessageChat && m.UserID == "carol") {
+			t.Fatalf("alice recibió un mensaje que debía rechazarse: %+v", m)
+		}
+	}
+}
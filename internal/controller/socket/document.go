@@ -0,0 +1,318 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"gorm.io/datatypes"
+)
+
+const (
+	// persistDelay espera a que las ediciones se calmen antes de guardar el documento
+	persistDelay = 2 * time.Second
+	// persistMaxDelay limita cuánto puede quedar sin guardar una sala con ediciones continuas
+	persistMaxDelay = 10 * time.Second
+
+	// opLogSize es cuántas operaciones recuerda la sala para rebasar ediciones atrasadas
+	opLogSize = 500
+)
+
+// Tipos de mensaje de la edición en vivo
+const (
+	MessageOp         = "op"          // Cliente -> servidor: operación a aplicar. Servidor -> sala: operación aceptada
+	MessageOpRejected = "op_rejected" // Servidor -> remitente: la operación no se pudo aplicar
+	MessageDocument   = "document"    // Servidor -> cliente: estado completo del documento al unirse
+)
+
+// editRequest es el contenido de un mensaje "op" enviado por un cliente
+type editRequest struct {
+	OpID string `json:"op_id,omitempty"` // Identificador del cliente para reconocer la respuesta
+	// BaseSeq es la última operación del servidor que el cliente conocía al crear la suya;
+	// si falta se asume que conocía todas
+	BaseSeq *int64 `json:"base_seq,omitempty"`
+	content.Operation
+}
+
+var errResyncRequired = errors.New("base_seq demasiado antiguo, se envía el documento actual")
+
+// appliedOp es una entrada del log de operaciones de la sala
+type appliedOp struct {
+	Seq    int64             `json:"seq"`
+	Op     content.Operation `json:"op"` // Lo aplicado, para volver a aplicarlo si se recarga el documento
+	Effect content.Effect    `json:"effect"`
+}
+
+// clientOp es una operación con la conexión que la envió. Si llegó de otra instancia
+// client es nil y solo se conoce su identidad.
+type clientOp struct {
+	client   *Client
+	ClientID string      `json:"client_id"`
+	UserID   string      `json:"user_id"`
+	Username string      `json:"username"`
+	Request  editRequest `json:"request"`
+	// History es MessageUndo o MessageRedo si la operación se toma del historial del usuario
+	History string `json:"history,omitempty"`
+	// Reload reemplaza el documento en lugar de aplicar Request; lo envía la instancia que
+	// guarda cuando el Content cambió fuera de la sala
+	Reload *documentReload `json:"reload,omitempty"`
+}
+
+// liveDocument es el Content autoritativo de una sala mientras está activa. Solo lo
+// modifica la goroutine de la sala.
+type liveDocument struct {
+	content    map[string]interface{}
+	seq        int64                   // Número de la última operación aplicada
+	revision   int                     // Revisión del proyecto que refleja el documento
+	savedSeq   int64                   // Última operación incluida en lo guardado
+	log        []appliedOp             // Últimas operaciones aplicadas, en orden
+	history    map[string]*userHistory // Deshacer y rehacer de cada usuario
+	loaded     bool
+	dirty      bool
+	dirtySince time.Time
+}
+
+// SubmitOp encola la operación de un cliente para que la aplique la goroutine de la sala
+func (r *Room) SubmitOp(client *Client, request editRequest) {
+	select {
+	case r.ops <- clientOp{client: client, ClientID: client.ID, UserID: client.UserID, Username: client.Username, Request: request}:
+	case <-r.done:
+	}
+}
+
+// loadDocument carga el Content y la capacidad del proyecto al abrir la sala
+func (r *Room) loadDocument(hub *Hub) {
+	project, err := hub.projects.GetProjectByID(r.ID)
+	if err != nil {
+		log.Printf("Error cargando el documento de la sala %s: %v", r.ID, err)
+		return
+	}
+	r.setCapacity(hub.capacity.forProject(project))
+
+	doc, err := content.Parse(project.Content)
+	if err != nil {
+		log.Printf("Documento inválido en la sala %s: %v", r.ID, err)
+		return
+	}
+	r.document.content = doc
+	r.document.revision = project.Revision
+	r.document.loaded = true
+}
+
+// canEdit verifica que quien envió la operación pueda editar, y si no la rechaza
+func (r *Room) canEdit(op clientOp) bool {
+	switch {
+	case op.client.Role == entity.ProjectRoleViewer:
+		r.rejectOp(op, "No tienes permiso para editar este proyecto")
+	case op.client.Spectator:
+		r.rejectOp(op, "Estás conectado como espectador")
+	default:
+		return true
+	}
+	return false
+}
+
+// applyOp rebasa la operación de un cliente sobre las que se aplicaron desde su base_seq,
+// la aplica y la retransmite a la sala con su número de secuencia
+func (r *Room) applyOp(op clientOp) bool {
+	if op.Reload != nil {
+		r.replaceDocument(*op.Reload)
+		return false
+	}
+	if !r.document.loaded {
+		r.rejectOp(op, "El documento de la sala no está disponible")
+		return false
+	}
+
+	var operation content.Operation
+	if op.History != "" {
+		var err error
+		if operation, err = r.historyOp(op); err != nil {
+			r.rejectOp(op, err.Error())
+			r.sendHistory(op)
+			return false
+		}
+	} else {
+		var ok bool
+		var err error
+		operation, ok, err = r.rebase(op)
+		if err != nil {
+			// El cliente está demasiado atrasado: debe partir del documento actual
+			r.rejectOp(op, err.Error())
+			r.sendDocument(op.client)
+			return false
+		}
+		if !ok {
+			r.rejectOp(op, "La operación quedó sin efecto por un cambio concurrente")
+			return false
+		}
+	}
+
+	inverse, invertErr := content.Invert(r.document.content, operation)
+	effect, err := content.Apply(r.document.content, operation)
+	if err != nil {
+		r.rejectOp(op, err.Error())
+		if op.History != "" {
+			r.sendHistory(op)
+		}
+		return false
+	}
+
+	r.document.seq++
+	r.document.log = append(r.document.log, appliedOp{Seq: r.document.seq, Op: operation, Effect: effect})
+	if len(r.document.log) > opLogSize {
+		r.document.log = r.document.log[len(r.document.log)-opLogSize:]
+	}
+	if !r.document.dirty {
+		r.document.dirty = true
+		r.document.dirtySince = time.Now()
+	}
+
+	if invertErr == nil {
+		r.recordHistory(op, inverse)
+	}
+
+	// Cada instancia aplica la operación y la envía a sus propios clientes
+	data := map[string]interface{}{
+		"seq":   r.document.seq,
+		"op_id": op.Request.OpID,
+		"op":    operation,
+	}
+	if op.History != "" {
+		data["history"] = op.History
+	}
+	r.broadcastLocal(Message{
+		Type:      MessageOp,
+		ProjectID: r.ID,
+		UserID:    op.UserID,
+		Username:  op.Username,
+		Data:      data,
+	})
+	if op.History != "" {
+		r.sendHistory(op)
+	}
+	return true
+}
+
+// rebase transforma la operación contra todas las aplicadas después de base_seq, también
+// las del mismo cliente: con una sola operación en vuelo, base_seq ya incluye las suyas
+func (r *Room) rebase(op clientOp) (content.Operation, bool, error) {
+	operation := op.Request.Operation
+	if op.Request.BaseSeq == nil || *op.Request.BaseSeq >= r.document.seq {
+		return operation, true, nil
+	}
+
+	base := *op.Request.BaseSeq
+	if len(r.document.log) == 0 || r.document.log[0].Seq > base+1 {
+		return operation, false, errResyncRequired
+	}
+
+	operation, ok := content.Rebase(r.document.content, operation, r.effectsSince(base))
+	return operation, ok, nil
+}
+
+// effectsSince retorna los efectos de las operaciones aplicadas después de seq, en orden
+func (r *Room) effectsSince(seq int64) []content.Effect {
+	var effects []content.Effect
+	for _, applied := range r.document.log {
+		if applied.Seq > seq {
+			effects = append(effects, applied.Effect)
+		}
+	}
+	return effects
+}
+
+func (r *Room) rejectOp(op clientOp, reason string) {
+	if op.client == nil {
+		// La envió un cliente de otra instancia; esa instancia le responde
+		return
+	}
+	r.sendTo(op.client, Message{
+		Type:      MessageOpRejected,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"op_id": op.Request.OpID,
+			"error": reason,
+		},
+	})
+}
+
+// sendDocument envía al cliente el estado actual del documento
+func (r *Room) sendDocument(client *Client) {
+	if !r.document.loaded {
+		return
+	}
+	r.sendTo(client, Message{
+		Type:      MessageDocument,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"seq":     r.document.seq,
+			"content": r.document.content,
+		},
+	})
+}
+
+// persistDue retorna cuánto esperar para guardar, respetando persistMaxDelay
+func (r *Room) persistDue() time.Duration {
+	remaining := persistMaxDelay - time.Since(r.document.dirtySince)
+	if remaining < persistDelay {
+		return max(remaining, 0)
+	}
+	return persistDelay
+}
+
+// persist guarda el documento en el proyecto si hubo cambios desde el último guardado. Con
+// varias instancias solo guarda la elegida por writer y avisa a las demás hasta qué operación
+// quedó guardado.
+func (r *Room) persist(hub *Hub) {
+	if !r.document.loaded || !r.document.dirty || !r.writer() {
+		return
+	}
+
+	raw, err := json.Marshal(r.document.content)
+	if err != nil {
+		log.Printf("Error serializando el documento de la sala %s: %v", r.ID, err)
+		return
+	}
+
+	project, err := hub.projects.SaveRoomContent(r.ID, datatypes.JSON(raw), r.document.revision)
+	if errors.Is(err, repositories.ErrRevisionConflict) {
+		// El proyecto se cambió fuera de la sala: vale esa versión
+		log.Printf("El documento de la sala %s cambió en la base, se recarga", r.ID)
+		r.reloadDocument(hub)
+		return
+	}
+	if err != nil {
+		log.Printf("Error guardando el documento de la sala %s: %v", r.ID, err)
+		return
+	}
+
+	r.document.dirty = false
+	r.document.revision = project.Revision
+	r.document.savedSeq = r.document.seq
+	r.publish(kindSaved, savedDocument{Seq: r.document.seq, Revision: project.Revision})
+	log.Printf("Documento de la sala %s guardado (revisión %d, operación %d)", r.ID, project.Revision, r.document.seq)
+}
+
+// sendTo envía un mensaje solo a un cliente, sin bloquear la sala. Solo se usa desde run.
+func (r *Room) sendTo(client *Client, message Message) {
+	frame, err := newFrame(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+	r.sendFrame(client, frame, framePriority(message.Type))
+}
+
+// sendFrame escribe un frame ya serializado en el canal del cliente, sin bloquear la sala
+func (r *Room) sendFrame(client *Client, frame *frame, priority framePriorityLevel) {
+	if !r.Clients[client] && !r.isWaiting(client) {
+		// El cliente ya salió y su canal está cerrado
+		return
+	}
+	r.enqueue(client, frame, priority)
+}
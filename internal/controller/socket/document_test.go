@@ -0,0 +1,59 @@
+package socket
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+func newTestRoom(doc map[string]interface{}) *Room {
+	return &Room{
+		ID:       "project",
+		Clients:  make(map[*Client]bool),
+		document: &liveDocument{content: doc, loaded: true},
+		frames:   newReplayBuffer(),
+	}
+}
+
+func childOrder(doc map[string]interface{}) []string {
+	var ids []string
+	for _, child := range doc[content.ChildrenKey].([]interface{}) {
+		ids = append(ids, child.(map[string]interface{})[content.IDKey].(string))
+	}
+	return ids
+}
+
+// Una operación se rebasa sobre todas las posteriores a su base_seq, también las del mismo
+// cliente, y la raíz se reconoce por su id real
+func TestApplyOpRebasesOverEveryLaterOp(t *testing.T) {
+	room := newTestRoom(map[string]interface{}{
+		content.IDKey: "root",
+		content.ChildrenKey: []interface{}{
+			map[string]interface{}{content.IDKey: "a"},
+			map[string]interface{}{content.IDKey: "b"},
+		},
+	})
+	base := int64(0)
+	insert := func(clientID, nodeID string, index int) {
+		op := clientOp{ClientID: clientID, Request: editRequest{BaseSeq: &base, Operation: content.Operation{
+			Type:     content.OpInsertNode,
+			NodeID:   nodeID,
+			ParentID: "root",
+			Index:    index,
+			Node:     map[string]interface{}{},
+		}}}
+		if !room.applyOp(op) {
+			t.Fatalf("la operación de %s fue rechazada", nodeID)
+		}
+	}
+
+	insert("A", "x", 0) // Antes de a
+	insert("B", "y", 2) // Después de b
+	insert("A", "z", 1) // Entre a y b
+
+	want := []string{"x", "a", "z", "b", "y"}
+	if got := childOrder(room.document.content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("se esperaba %v, se obtuvo %v", want, got)
+	}
+}
@@ -0,0 +1,111 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// Codificaciones de los frames, elegidas con ?encoding= al conectar
+const (
+	EncodingJSON    = "json"    // Frames de texto JSON (por defecto)
+	EncodingMsgpack = "msgpack" // Frames binarios MessagePack con la misma estructura
+)
+
+// compressThreshold es el tamaño desde el cual conviene comprimir un frame con permessage-deflate
+const compressThreshold = 512
+
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.WriteExt = true
+	h.RawToString = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}()
+
+// frame es un mensaje serializado una sola vez. Cada codificación se prepara la primera vez
+// que una conexión la necesita y la comparten todas; websocket.PreparedMessage guarda además
+// la versión comprimida.
+type frame struct {
+	data     []byte // JSON; es inmutable, así que se puede leer desde cualquier goroutine
+	prepared [2]*websocket.PreparedMessage
+	err      [2]error
+	once     [2]sync.Once
+}
+
+func newFrame(message Message) (*frame, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return &frame{data: data}, nil
+}
+
+// preparedFor retorna el frame listo para escribir con la codificación de la conexión
+func (f *frame) preparedFor(encoding string) (*websocket.PreparedMessage, error) {
+	i, messageType := 0, websocket.TextMessage
+	if encoding == EncodingMsgpack {
+		i, messageType = 1, websocket.BinaryMessage
+	}
+
+	f.once[i].Do(func() {
+		data := f.data
+		if messageType == websocket.BinaryMessage {
+			if data, f.err[i] = jsonToMsgpack(f.data); f.err[i] != nil {
+				return
+			}
+		}
+		f.prepared[i], f.err[i] = websocket.NewPreparedMessage(messageType, data)
+	})
+	return f.prepared[i], f.err[i]
+}
+
+// jsonToMsgpack recodifica un frame JSON. Se parte del JSON y no del Message porque sus datos
+// pueden seguir cambiando en la goroutine de la sala.
+func jsonToMsgpack(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(normalizeNumbers(value))
+	return out, err
+}
+
+// msgpackToJSON convierte un frame MessagePack de un cliente al JSON que espera el catálogo
+func msgpackToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// normalizeNumbers convierte los json.Number en enteros o flotantes para que MessagePack
+// los codifique como números
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,138 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+	"github.com/gorilla/websocket"
+)
+
+// benchConns abre n conexiones y retorna el lado del servidor; el del cliente descarta lo
+// que recibe
+func benchConns(b *testing.B, n int) []*websocket.Conn {
+	b.Helper()
+	accepted := make(chan *websocket.Conn)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}))
+	b.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conns := make([]*websocket.Conn, n)
+	for i := range conns {
+		client, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		go func() {
+			for {
+				if _, _, err := client.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+		conns[i] = <-accepted
+		b.Cleanup(func() {
+			conns[i].Close()
+			client.Close()
+		})
+	}
+	return conns
+}
+
+// largeEdit es la operación que inserta una pantalla completa, de unos 60 KB en JSON
+func largeEdit(seq int) Message {
+	children := make([]interface{}, 300)
+	for i := range children {
+		children[i] = map[string]interface{}{
+			content.IDKey:   fmt.Sprintf("text-%d-%d", seq, i),
+			content.TypeKey: "Text",
+			"text":          fmt.Sprintf("Elemento %d de la lista de productos", i),
+			"style":         map[string]interface{}{"fontSize": 14, "color": "#212121", "padding": []interface{}{8, 16, 8, 16}},
+		}
+	}
+	return Message{
+		Type:      MessageOp,
+		ProjectID: "project",
+		UserID:    "user",
+		Username:  "user",
+		Data: map[string]interface{}{
+			"seq": seq,
+			"op": content.Operation{
+				Type:     content.OpInsertNode,
+				NodeID:   fmt.Sprintf("screen-%d", seq),
+				ParentID: content.RootID,
+				Node:     map[string]interface{}{content.TypeKey: "Column", content.ChildrenKey: children},
+			},
+		},
+	}
+}
+
+// BenchmarkBroadcast compara serializar el frame en cada conexión con serializarlo una vez y
+// escribir el PreparedMessage compartido, como hace la sala
+func BenchmarkBroadcast(b *testing.B) {
+	for _, encoding := range []string{EncodingJSON, EncodingMsgpack} {
+		for _, clients := range []int{10, 100} {
+			b.Run(fmt.Sprintf("%s/clients=%d/per_connection", encoding, clients), func(b *testing.B) {
+				conns := benchConns(b, clients)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					message := largeEdit(i)
+					for _, conn := range conns {
+						if err := writePerConnection(conn, encoding, message); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+
+			b.Run(fmt.Sprintf("%s/clients=%d/prepared", encoding, clients), func(b *testing.B) {
+				conns := benchConns(b, clients)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					f, err := newFrame(largeEdit(i))
+					if err != nil {
+						b.Fatal(err)
+					}
+					for _, conn := range conns {
+						prepared, err := f.preparedFor(encoding)
+						if err != nil {
+							b.Fatal(err)
+						}
+						conn.EnableWriteCompression(len(f.data) >= compressThreshold)
+						if err := conn.WritePreparedMessage(prepared); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// writePerConnection serializa y comprime el mensaje solo para esta conexión
+func writePerConnection(conn *websocket.Conn, encoding string, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	messageType := websocket.TextMessage
+	if encoding == EncodingMsgpack {
+		if data, err = jsonToMsgpack(data); err != nil {
+			return err
+		}
+		messageType = websocket.BinaryMessage
+	}
+	conn.EnableWriteCompression(len(data) >= compressThreshold)
+	return conn.WriteMessage(messageType, data)
+}
@@ -1,30 +1,70 @@
 package socket
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Handler maneja las conexiones WebSocket
 type Handler struct {
-	hub *Hub
+	hub      *Hub
+	auth     *Authenticator
+	projects services.ProjectService
 }
 
 // NewHandler crea una nueva instancia del handler
-func NewHandler() *Handler {
-	hub := NewHub()
+func NewHandler(deps Dependencies, auth *Authenticator) *Handler {
+	hub := NewHub(deps)
 	go hub.Run() // Iniciar el hub en una goroutine separada
 
 	return &Handler{
-		hub: hub,
+		hub:      hub,
+		auth:     auth,
+		projects: deps.Projects,
 	}
 }
 
 // HandleWebSocket retorna el handler de gin para WebSocket
 func (h *Handler) HandleWebSocket() gin.HandlerFunc {
-	return WebSocketHandler(h.hub)
+	return WebSocketHandler(h.hub, h.auth, h.projects)
+}
+
+// Shutdown cierra las salas avisando a los clientes y guarda sus documentos
+func (h *Handler) Shutdown(ctx context.Context) error {
+	return h.hub.Shutdown(ctx)
+}
+
+// IssueTicket entrega un ticket de conexión de corta duración al usuario autenticado por JWTMiddleware
+func (h *Handler) IssueTicket(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+		return
+	}
+
+	value, expiresAt, err := h.auth.IssueTicket(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar el ticket"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ticket":     value,
+		"expires_at": expiresAt,
+	})
 }
 
 // GetRoomInfo obtiene información de una sala
@@ -35,37 +75,36 @@ func (h *Handler) GetRoomInfo(c *gin.Context) {
 		return
 	}
 
-	// Validar autenticación
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" || !h.isUserAuthenticated(c, userID) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+	// Validar autenticación y acceso al proyecto
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	if _, ok := projectAccess(c, h.projects, projectID, user.ID); !ok {
 		return
 	}
 
 	room := h.hub.GetRoom(projectID)
 	if room == nil {
+		capacity := h.hub.capacity
+		if project, err := h.projects.GetProjectByID(projectID); err == nil {
+			capacity = capacity.forProject(project)
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"project_id":      projectID,
-			"users_count":     0,
-			"max_users":       4,
-			"connected_users": []interface{}{},
-			"is_full":         false,
+			"project_id":       projectID,
+			"users_count":      0,
+			"editors_count":    0,
+			"spectators_count": 0,
+			"waiting_count":    0,
+			"max_users":        capacity.Editors,
+			"max_spectators":   capacity.Spectators,
+			"connected_users":  []interface{}{},
+			"is_full":          false,
 		})
 		return
 	}
 
-	room.mutex.RLock()
-	usersCount := len(room.Clients)
-	connectedUsers := room.GetConnectedUsers()
-	room.mutex.RUnlock()
-
-	c.JSON(http.StatusOK, gin.H{
-		"project_id":      projectID,
-		"users_count":     usersCount,
-		"max_users":       room.MaxUsers,
-		"connected_users": connectedUsers,
-		"is_full":         usersCount >= room.MaxUsers,
-	})
+	c.JSON(http.StatusOK, room.info())
 }
 
 // SendMessage envía un mensaje a una sala específica (endpoint REST)
@@ -77,10 +116,9 @@ func (h *Handler) SendMessage(c *gin.Context) {
 	}
 
 	var req struct {
-		Type     string      `json:"type" binding:"required"`
-		Data     interface{} `json:"data"`
-		UserID   string      `json:"user_id" binding:"required"`
-		Username string      `json:"username" binding:"required"`
+		Type string          `json:"type" binding:"required"`
+		Data json.RawMessage `json:"data"`
+		To   []string        `json:"to"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -88,9 +126,12 @@ func (h *Handler) SendMessage(c *gin.Context) {
 		return
 	}
 
-	// Validar autenticación
-	if !h.isUserAuthenticated(c, req.UserID) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+	// Validar autenticación; el remitente es el usuario del token
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	if _, ok := projectAccess(c, h.projects, projectID, user.ID); !ok {
 		return
 	}
 
@@ -99,17 +140,47 @@ func (h *Handler) SendMessage(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Sala no encontrada"})
 		return
 	}
+	muted, err := h.hub.sanction(projectID, user.ID, entity.ModerationMute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las sanciones del usuario"})
+		return
+	}
+	if muted != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Un moderador te silenció en esta sala"})
+		return
+	}
+
+	// Por REST solo se pueden enviar los mensajes del catálogo que se retransmiten a la sala
+	spec, data, errPayload := decodePayload(req.Type, req.Data)
+	if errPayload == nil && !spec.relay {
+		errPayload = &ErrorPayload{Code: ErrorCodeUnknownType, Message: "este tipo de mensaje no se puede enviar por REST", Type: req.Type}
+	}
+	if errPayload == nil && len(req.To) > 0 {
+		// Los mensajes dirigidos solo se envían por WebSocket, donde se verifican los destinatarios
+		errPayload = &ErrorPayload{Code: ErrorCodeInvalid, Message: "to solo se admite por WebSocket", Type: req.Type}
+	}
+	if errPayload != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errPayload.Message, "code": errPayload.Code})
+		return
+	}
 
 	message := Message{
 		Type:      req.Type,
-		Data:      req.Data,
+		Data:      data,
 		ProjectID: projectID,
-		UserID:    req.UserID,
-		Username:  req.Username,
+		UserID:    user.ID.String(),
+		Username:  displayName(user),
 	}
 
 	if err := room.BroadcastToRoom(message); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error enviando mensaje"})
+		switch {
+		case errors.Is(err, ErrRoomBusy):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrRoomClosed):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sala no encontrada"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error enviando mensaje"})
+		}
 		return
 	}
 
@@ -122,26 +193,15 @@ func (h *Handler) SendMessage(c *gin.Context) {
 // GetActiveRooms obtiene todas las salas activas
 func (h *Handler) GetActiveRooms(c *gin.Context) {
 	// Validar que el usuario esté autenticado
-	userID := c.GetHeader("X-User-ID")
-	if userID == "" || !h.isUserAuthenticated(c, userID) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+	if _, ok := h.authenticate(c); !ok {
 		return
 	}
 
 	h.hub.mutex.RLock()
-	rooms := make([]map[string]interface{}, 0, len(h.hub.rooms))
-
-	for projectID, room := range h.hub.rooms {
-		room.mutex.RLock()
-		roomInfo := map[string]interface{}{
-			"project_id":      projectID,
-			"users_count":     len(room.Clients),
-			"max_users":       room.MaxUsers,
-			"is_full":         len(room.Clients) >= room.MaxUsers,
-			"connected_users": room.GetConnectedUsers(),
-		}
-		room.mutex.RUnlock()
-		rooms = append(rooms, roomInfo)
+	rooms := make([]gin.H, 0, len(h.hub.rooms))
+
+	for _, room := range h.hub.rooms {
+		rooms = append(rooms, room.info())
 	}
 	h.hub.mutex.RUnlock()
 
@@ -151,93 +211,211 @@ func (h *Handler) GetActiveRooms(c *gin.Context) {
 	})
 }
 
-// KickUser expulsa a un usuario de una sala (solo para administradores)
-func (h *Handler) KickUser(c *gin.Context) {
-	projectID := c.Param("project_id")
-	targetUserID := c.Param("user_id")
+// sanctionRequest es el cuerpo de las peticiones de silencio y ban
+type sanctionRequest struct {
+	DurationSeconds int64  `json:"duration_seconds"`
+	Reason          string `json:"reason"`
+}
+
+const maxModerationReason = 500
 
-	if projectID == "" || targetUserID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id y user_id son requeridos"})
+// KickUser desconecta a un usuario de la sala en todas sus conexiones
+func (h *Handler) KickUser(c *gin.Context) {
+	projectID, actor, target, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+	reason := c.Query("reason")
+	if utf8.RuneCountInString(reason) > maxModerationReason {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason es demasiado largo"})
 		return
 	}
 
-	// Validar autenticación del usuario que quiere expulsar
-	adminUserID := c.GetHeader("X-User-ID")
-	if adminUserID == "" || !h.isUserAuthenticated(c, adminUserID) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+	if err := h.hub.moderation.Kick(projectID, actor.ID, target, reason); err != nil {
+		moderationError(c, err)
 		return
 	}
+	h.hub.moderate(projectID, moderationAction{
+		Action:  entity.ModerationKick,
+		UserID:  target.String(),
+		ActorID: actor.ID.String(),
+		Reason:  reason,
+	})
 
-	// Aquí deberías validar que el usuario tenga permisos de administrador
-	// if !h.isUserAdmin(adminUserID, projectID) { ... }
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Usuario expulsado correctamente",
+		"user_id": target.String(),
+	})
+}
 
-	room := h.hub.GetRoom(projectID)
-	if room == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Sala no encontrada"})
+// MuteUser impide que un usuario envíe chat o ediciones; duration_seconds 0 es hasta que se levante
+func (h *Handler) MuteUser(c *gin.Context) {
+	h.sanction(c, entity.ModerationMute)
+}
+
+// BanUser desconecta a un usuario y le impide volver a la sala durante duration_seconds
+func (h *Handler) BanUser(c *gin.Context) {
+	h.sanction(c, entity.ModerationBan)
+}
+
+func (h *Handler) UnmuteUser(c *gin.Context) {
+	h.liftSanction(c, entity.ModerationUnmute)
+}
+
+func (h *Handler) UnbanUser(c *gin.Context) {
+	h.liftSanction(c, entity.ModerationUnban)
+}
+
+// GetModerationLog retorna las últimas acciones de moderación del proyecto
+func (h *Handler) GetModerationLog(c *gin.Context) {
+	projectID := c.Param("project_id")
+	user, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+	if _, ok := projectAccess(c, h.projects, projectID, user.ID); !ok {
+		return
+	}
+	if h.hub.moderation == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "La moderación no está disponible"})
 		return
 	}
 
-	// Buscar el cliente a expulsar
-	room.mutex.RLock()
-	var targetClient *Client
-	for client := range room.Clients {
-		if client.UserID == targetUserID {
-			targetClient = client
-			break
-		}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	entries, err := h.hub.moderation.GetLog(projectID, user.ID, limit)
+	if err != nil {
+		moderationError(c, err)
+		return
 	}
-	room.mutex.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
 
-	if targetClient == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado en la sala"})
+func (h *Handler) sanction(c *gin.Context, action string) {
+	projectID, actor, target, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+	var req sanctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if utf8.RuneCountInString(req.Reason) > maxModerationReason {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason es demasiado largo"})
+		return
+	}
+	if req.DurationSeconds < 0 || req.DurationSeconds > math.MaxInt64/int64(time.Second) {
+		moderationError(c, services.ErrInvalidDuration)
 		return
 	}
 
-	// Enviar mensaje de expulsión antes de desconectar
-	kickMessage := Message{
-		Type:      "kicked",
-		ProjectID: projectID,
-		UserID:    targetUserID,
-		Username:  targetClient.Username,
-		Data: map[string]interface{}{
-			"reason": "Expulsado por administrador",
-			"admin":  adminUserID,
-		},
+	apply := h.hub.moderation.Mute
+	if action == entity.ModerationBan {
+		apply = h.hub.moderation.Ban
 	}
+	sanction, err := apply(projectID, actor.ID, target, time.Duration(req.DurationSeconds)*time.Second, req.Reason)
+	if err != nil {
+		moderationError(c, err)
+		return
+	}
+	h.hub.moderate(projectID, moderationAction{
+		Action:    action,
+		UserID:    target.String(),
+		ActorID:   actor.ID.String(),
+		Reason:    sanction.Reason,
+		ExpiresAt: sanction.ExpiresAt,
+	})
+	c.JSON(http.StatusCreated, sanction)
+}
 
-	room.BroadcastToRoom(kickMessage)
+func (h *Handler) liftSanction(c *gin.Context, action string) {
+	projectID, actor, target, ok := h.moderationTarget(c)
+	if !ok {
+		return
+	}
+	lift := h.hub.moderation.Unmute
+	if action == entity.ModerationUnban {
+		lift = h.hub.moderation.Unban
+	}
+	if err := lift(projectID, actor.ID, target); err != nil {
+		moderationError(c, err)
+		return
+	}
+	if action == entity.ModerationUnmute {
+		h.hub.moderate(projectID, moderationAction{Action: action, UserID: target.String(), ActorID: actor.ID.String()})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sanción levantada", "user_id": target.String()})
+}
 
-	// Desconectar al usuario
-	h.hub.unregister <- targetClient
+// moderationTarget autentica al moderador, verifica su acceso al proyecto y lee el usuario a moderar.
+// Que sea dueño o administrador lo verifica el servicio de moderación.
+func (h *Handler) moderationTarget(c *gin.Context) (string, *entity.User, uuid.UUID, bool) {
+	projectID := c.Param("project_id")
+	target, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id inválido"})
+		return "", nil, uuid.Nil, false
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Usuario expulsado correctamente",
-		"user_id": targetUserID,
-	})
+	actor, ok := h.authenticate(c)
+	if !ok {
+		return "", nil, uuid.Nil, false
+	}
+	if _, ok := projectAccess(c, h.projects, projectID, actor.ID); !ok {
+		return "", nil, uuid.Nil, false
+	}
+	if h.hub.moderation == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "La moderación no está disponible"})
+		return "", nil, uuid.Nil, false
+	}
+	return projectID, actor, target, true
 }
 
-// isUserAuthenticated valida si el usuario está autenticado
-func (h *Handler) isUserAuthenticated(c *gin.Context, userID string) bool {
-	// Implementar según tu sistema de autenticación
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		return false
+func moderationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes permiso para moderar a este usuario"})
+	case errors.Is(err, services.ErrInvalidTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No puedes moderar al dueño del proyecto ni a ti mismo"})
+	case errors.Is(err, services.ErrInvalidDuration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_seconds debe ser de hasta 365 días, y mayor a 0 para un ban"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "El usuario no tiene esa sanción"})
+	default:
+		log.Printf("Error de moderación: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error aplicando la moderación"})
 	}
+}
 
-	// Ejemplo con Bearer token
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		// Aquí validarías el JWT token
-		// return validateJWTToken(token, userID)
-		return token != "" && len(token) > 10 // Placeholder
+// authenticate verifica el JWT de la petición y responde 401 si no es válido
+func (h *Handler) authenticate(c *gin.Context) (*entity.User, bool) {
+	user, _, err := h.auth.Authenticate(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+		return nil, false
 	}
-
-	return false
+	return user, true
 }
 
-// isUserAdmin verifica si el usuario es administrador del proyecto (implementar según necesidades)
-func (h *Handler) isUserAdmin(userID, projectID string) bool {
-	// Implementar validación de permisos de administrador
-	// Podría consultar la base de datos para verificar roles
-	return false // Placeholder
+// projectAccess verifica que el proyecto exista y que el usuario tenga acceso, respondiendo
+// 400, 404 o 403 si no es así. Retorna el rol del usuario en el proyecto.
+func projectAccess(c *gin.Context, projects services.ProjectService, projectID string, userID uuid.UUID) (string, bool) {
+	if _, err := uuid.Parse(projectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id inválido"})
+		return "", false
+	}
+
+	role, err := projects.GetUserRole(projectID, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proyecto no encontrado"})
+		return "", false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el acceso al proyecto"})
+		return "", false
+	case role == "":
+		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes acceso a este proyecto"})
+		return "", false
+	}
+	return role, true
 }
@@ -0,0 +1,157 @@
+package socket
+
+import (
+	"errors"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+// defaultUndoDepth es cuántas operaciones puede deshacer cada usuario si no se configura otro valor
+const defaultUndoDepth = 100
+
+// Tipos de mensaje del historial de cada usuario
+const (
+	MessageUndo    = "undo"    // Cliente -> servidor: deshacer la última operación propia
+	MessageRedo    = "redo"    // Cliente -> servidor: rehacer la última operación deshecha
+	MessageHistory = "history" // Servidor -> remitente: estado del historial después de undo o redo
+)
+
+var (
+	errNothingToUndo = errors.New("No hay operaciones para deshacer")
+	errNothingToRedo = errors.New("No hay operaciones para rehacer")
+	errHistoryStale  = errors.New("La operación es demasiado antigua para deshacerla")
+	errHistoryLost   = errors.New("La operación ya no se puede deshacer por un cambio concurrente")
+)
+
+// HistoryRequest es el contenido de los mensajes "undo" y "redo"
+type HistoryRequest struct {
+	OpID string `json:"op_id,omitempty"` // Se devuelve en el op resultante o en op_rejected
+}
+
+func (p *HistoryRequest) Validate() error { return nil }
+
+// historyEntry es una operación que se puede deshacer o rehacer: Inverse la revierte y
+// vale para el documento tal como quedó en Seq
+type historyEntry struct {
+	Seq     int64             `json:"seq"`
+	Inverse content.Operation `json:"inverse"`
+}
+
+// userHistory son las pilas de deshacer y rehacer de un usuario, la más reciente al final
+type userHistory struct {
+	Undo []historyEntry `json:"undo"`
+	Redo []historyEntry `json:"redo"`
+}
+
+// SubmitHistory encola un undo o redo. Se resuelve al aplicarlo, en el mismo orden que las
+// demás operaciones, para que todas las instancias calculen la misma inversa.
+func (r *Room) SubmitHistory(client *Client, kind string, request HistoryRequest) {
+	op := clientOp{
+		client:   client,
+		ClientID: client.ID,
+		UserID:   client.UserID,
+		Username: client.Username,
+		Request:  editRequest{OpID: request.OpID},
+		History:  kind,
+	}
+	select {
+	case r.ops <- op:
+	case <-r.done:
+	}
+}
+
+// historyOp saca de la pila la operación a deshacer o rehacer y la transforma contra las
+// que se aplicaron después. Una entrada que ya no se puede aplicar se descarta.
+func (r *Room) historyOp(op clientOp) (content.Operation, error) {
+	history := r.document.history[op.UserID]
+	var stack *[]historyEntry
+	switch {
+	case history != nil && op.History == MessageUndo:
+		stack = &history.Undo
+	case history != nil && op.History == MessageRedo:
+		stack = &history.Redo
+	}
+	if stack == nil || len(*stack) == 0 {
+		if op.History == MessageRedo {
+			return content.Operation{}, errNothingToRedo
+		}
+		return content.Operation{}, errNothingToUndo
+	}
+
+	entry := (*stack)[len(*stack)-1]
+	*stack = (*stack)[:len(*stack)-1]
+
+	if entry.Seq < r.document.seq && (len(r.document.log) == 0 || r.document.log[0].Seq > entry.Seq+1) {
+		// El log ya no tiene las operaciones posteriores; las entradas anteriores tampoco sirven
+		*stack = nil
+		return content.Operation{}, errHistoryStale
+	}
+
+	operation, ok := content.Rebase(r.document.content, entry.Inverse, r.effectsSince(entry.Seq))
+	if !ok {
+		return operation, errHistoryLost
+	}
+	return operation, nil
+}
+
+// recordHistory guarda la inversa de la operación recién aplicada en el historial de su
+// autor. Una edición nueva descarta lo que había para rehacer.
+func (r *Room) recordHistory(op clientOp, inverse content.Operation) {
+	if op.UserID == "" {
+		return
+	}
+	if r.document.history == nil {
+		r.document.history = make(map[string]*userHistory)
+	}
+	history := r.document.history[op.UserID]
+	if history == nil {
+		history = &userHistory{}
+		r.document.history[op.UserID] = history
+	}
+
+	entry := historyEntry{Seq: r.document.seq, Inverse: inverse}
+	switch op.History {
+	case MessageUndo:
+		history.Redo = pushHistory(history.Redo, entry, r.undoDepth())
+	case MessageRedo:
+		history.Undo = pushHistory(history.Undo, entry, r.undoDepth())
+	default:
+		history.Undo = pushHistory(history.Undo, entry, r.undoDepth())
+		history.Redo = nil
+	}
+}
+
+func pushHistory(stack []historyEntry, entry historyEntry, depth int) []historyEntry {
+	stack = append(stack, entry)
+	if len(stack) > depth {
+		stack = append([]historyEntry(nil), stack[len(stack)-depth:]...)
+	}
+	return stack
+}
+
+func (r *Room) undoDepth() int {
+	if r.hub == nil || r.hub.undoDepth <= 0 {
+		return defaultUndoDepth
+	}
+	return r.hub.undoDepth
+}
+
+// sendHistory informa al cliente cuántas operaciones puede deshacer y rehacer
+func (r *Room) sendHistory(op clientOp) {
+	if op.client == nil {
+		return
+	}
+	undo, redo := 0, 0
+	if history := r.document.history[op.UserID]; history != nil {
+		undo, redo = len(history.Undo), len(history.Redo)
+	}
+	r.sendTo(op.client, Message{
+		Type:      MessageHistory,
+		ProjectID: r.ID,
+		Data: map[string]interface{}{
+			"undo":  undo,
+			"redo":  redo,
+			"limit": r.undoDepth(),
+		},
+	})
+}
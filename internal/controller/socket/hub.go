@@ -1,15 +1,26 @@
 package socket
 
 import (
-	"encoding/json"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/backplane"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
 )
 
+// defaultIdleGrace es cuánto se mantiene abierta una sala vacía si no se configura otro valor
+const defaultIdleGrace = 30 * time.Second
+
 // Message representa un mensaje que se enviará por WebSocket
 type Message struct {
 	Type      string      `json:"type"`
+	Seq       int64       `json:"seq,omitempty"` // Número del frame en la sala; solo en los frames enviados a toda la sala
 	Data      interface{} `json:"data"`
+	To        []string    `json:"to,omitempty"` // Usuarios a los que se dirigió el mensaje; vacío si es para toda la sala
 	ProjectID string      `json:"project_id"`
 	UserID    string      `json:"user_id"`
 	Username  string      `json:"username"`
@@ -19,12 +30,53 @@ type Message struct {
 type Room struct {
 	ID         string           `json:"id"`        // Project ID
 	Clients    map[*Client]bool `json:"-"`         // Clientes conectados
-	Broadcast  chan []byte      `json:"-"`         // Canal para broadcast
+	Broadcast  chan Message     `json:"-"`         // Canal para broadcast
 	Register   chan *Client     `json:"-"`         // Canal para registrar cliente
 	Unregister chan *Client     `json:"-"`         // Canal para desregistrar cliente
-	MaxUsers   int              `json:"max_users"` // Máximo 4 usuarios
-	mutex      sync.RWMutex     `json:"-"`
-	done       chan struct{}    `json:"-"` // Canal para terminar la goroutine
+	MaxUsers   int              `json:"max_users"` // Lugares de editor
+
+	MaxSpectators int           `json:"max_spectators"` // Lugares de solo lectura
+	maxWaiting    int           // Largo máximo de la cola de espera
+	waiting       []*Client     // Editores esperando un lugar, en orden de llegada
+	mutex         sync.RWMutex  `json:"-"`
+	done          chan struct{} `json:"-"` // Se cierra cuando la sala sale del hub y termina run
+	stop          chan struct{} // Se cierra para apagar la sala con el servidor
+	stopOnce      sync.Once
+	closed        chan struct{} // Se cierra cuando run terminó de guardar y de cerrar los canales
+	hub           *Hub
+
+	document   *liveDocument         // Content autoritativo de la sala
+	ops        chan clientOp         // Operaciones de edición pendientes de aplicar
+	direct     chan directMessage    // Mensajes para un solo cliente
+	moderation chan moderationAction // Acciones de moderación de esta instancia
+	project    chan projectChange    // Cambios del proyecto hechos fuera de la sala
+	frames     *replayBuffer         // Numeración y últimos frames enviados a la sala
+
+	remote  chan backplane.Envelope // Sobres de las salas del proyecto en otras instancias
+	gap     chan struct{}           // Se perdieron sobres del backplane; la sala debe resincronizarse
+	cluster clusterState
+
+	evicted       []*Client    // Clientes a desconectar por no recibir frames; solo lo usa run
+	droppedFrames atomic.Int64 // Frames descartados en la sala, para diagnóstico
+
+	// Contadores de los límites de mensajes entrantes
+	throttledMessages   atomic.Int64 // Mensajes descartados por exceder un límite
+	throttledClients    atomic.Int64 // Conexiones a las que se les descartó algún mensaje
+	throttleDisconnects atomic.Int64 // Conexiones cerradas por seguir excediendo los límites
+
+	// Awareness de cada conexión; se protege con mutex y se envía agrupado
+	presence        map[*Client]*Presence
+	presenceDirty   map[*Client]bool
+	presenceRemoved []string
+	awarenessSignal chan struct{}
+}
+
+// directMessage es un mensaje dirigido a un cliente de la sala o, si client es nil, a todas
+// las conexiones de algunos usuarios
+type directMessage struct {
+	client  *Client
+	userIDs []string
+	message Message
 }
 
 // Hub mantiene el conjunto de clientes activos y les envía mensajes
@@ -32,19 +84,52 @@ type Hub struct {
 	rooms      map[string]*Room
 	register   chan *Client
 	unregister chan *Client
+	events     *event.Bus                 // Eventos de dominio (entradas y salidas de las salas)
+	projects   services.ProjectService    // Carga y guarda el Content de las salas
+	chat       services.ChatService       // Historial de chat de cada proyecto
+	moderation services.ModerationService // Silencios y expulsiones; nil si no hay moderación
+	capacity   Capacity                   // Límites globales de las salas
+	idleGrace  time.Duration              // Cuánto se mantiene una sala vacía antes de cerrarla
+	undoDepth  int                        // Operaciones que cada usuario puede deshacer
+	limits     Limits                     // Límites de los mensajes que envían los clientes
+	backplane  backplane.Backplane        // Conecta las salas con las de otras instancias; nil si hay una sola
+	node       string                     // Identifica a esta instancia en el backplane
 	mutex      sync.RWMutex
+
+	closing     bool           // El servidor se está apagando; no se abren salas nuevas
+	connections sync.WaitGroup // Conexiones cuyo writePump no terminó
 }
 
 // NewHub crea una nueva instancia del hub
-func NewHub() *Hub {
-	return &Hub{
+func NewHub(deps Dependencies) *Hub {
+	h := &Hub{
 		rooms:      make(map[string]*Room),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		events:     deps.Events,
+		projects:   deps.Projects,
+		chat:       deps.Chat,
+		moderation: deps.Moderation,
+		capacity:   deps.Capacity.withDefaults(),
+		limits:     deps.Limits.withDefaults(),
+		idleGrace:  deps.IdleGrace,
+		undoDepth:  deps.UndoDepth,
+		backplane:  deps.Backplane,
+		node:       uuid.NewString(),
+	}
+	if h.idleGrace <= 0 {
+		h.idleGrace = defaultIdleGrace
+	}
+	if h.backplane != nil {
+		h.backplane.Subscribe(h.receive)
 	}
+	if h.events != nil {
+		h.events.Subscribe(h.projectEvent)
+	}
+	return h
 }
 
-// CreateRoom crea una nueva sala para un proyecto
+// CreateRoom crea una nueva sala para un proyecto. Retorna nil si el hub se está cerrando.
 func (h *Hub) CreateRoom(projectID string) *Room {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -52,15 +137,37 @@ func (h *Hub) CreateRoom(projectID string) *Room {
 	if room, exists := h.rooms[projectID]; exists {
 		return room
 	}
+	if h.closing {
+		return nil
+	}
 
 	room := &Room{
 		ID:         projectID,
 		Clients:    make(map[*Client]bool),
-		Broadcast:  make(chan []byte, 256), // Buffer para evitar bloqueos
+		Broadcast:  make(chan Message, 256), // Buffer para evitar bloqueos
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
-		MaxUsers:   4,
+		MaxUsers:   h.capacity.Editors,
 		done:       make(chan struct{}),
+		stop:       make(chan struct{}),
+		closed:     make(chan struct{}),
+		hub:        h,
+		document:   &liveDocument{},
+		ops:        make(chan clientOp, 64),
+		direct:     make(chan directMessage, 64),
+		moderation: make(chan moderationAction, 16),
+		project:    make(chan projectChange, 16),
+		frames:     newReplayBuffer(),
+		remote:     make(chan backplane.Envelope, 1024),
+		gap:        make(chan struct{}, 1),
+		cluster:    clusterState{remote: make(map[string]*remoteNode)},
+
+		MaxSpectators: h.capacity.Spectators,
+		maxWaiting:    h.capacity.Waiting,
+
+		presence:        make(map[*Client]*Presence),
+		presenceDirty:   make(map[*Client]bool),
+		awarenessSignal: make(chan struct{}, 1),
 	}
 
 	h.rooms[projectID] = room
@@ -78,22 +185,27 @@ func (h *Hub) GetRoom(projectID string) *Room {
 	return h.rooms[projectID]
 }
 
-// RemoveRoom elimina una sala vacía
-func (h *Hub) RemoveRoom(projectID string) {
+// removeRoom saca la sala del hub y la cierra. Solo la llama run, que es quien sabe que la
+// sala sigue vacía. Se hace bajo el lock del hub para que Run no entregue un cliente a una
+// sala cerrada: o run lo recibe antes, o Run ve done cerrado y la sala ya no está en el mapa.
+func (h *Hub) removeRoom(room *Room) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if room, exists := h.rooms[projectID]; exists {
-		room.mutex.RLock()
-		clientsCount := len(room.Clients)
-		room.mutex.RUnlock()
-
-		if clientsCount == 0 {
-			// Señalar que la sala debe cerrarse
-			close(room.done)
-			delete(h.rooms, projectID)
-			log.Printf("Sala %s eliminada", projectID)
-		}
+	if h.rooms[room.ID] == room {
+		delete(h.rooms, room.ID)
+	}
+	close(room.done)
+	log.Printf("Sala %s eliminada", room.ID)
+}
+
+// removed indica si la sala ya salió del hub
+func (r *Room) removed() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -102,209 +214,380 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.register:
-			room := h.GetRoom(client.ProjectID)
-			if room == nil {
-				room = h.CreateRoom(client.ProjectID)
-			}
-			room.Register <- client
+			h.registerClient(client)
 
 		case client := <-h.unregister:
 			room := h.GetRoom(client.ProjectID)
 			if room != nil {
-				room.Unregister <- client
+				select {
+				case room.Unregister <- client:
+				case <-room.done:
+					// La sala ya cerró y con ella el canal del cliente
+				}
 			}
 		}
 	}
 }
 
+// registerClient entrega el cliente a la sala de su proyecto. Si la sala se cierra antes de
+// recibirlo, se entrega a una nueva.
+func (h *Hub) registerClient(client *Client) {
+	for {
+		room := h.CreateRoom(client.ProjectID)
+		if room == nil {
+			h.refuseShutdown(client)
+			return
+		}
+		select {
+		case room.Register <- client:
+			return
+		case <-room.done:
+		}
+	}
+}
+
 // run maneja los eventos de una sala específica
 func (r *Room) run(hub *Hub) {
+	r.loadDocument(hub)
+
+	// Con varias instancias el documento en vivo puede estar en otra; mientras llega no se
+	// aceptan operaciones propias
+	ops := r.ops
+	var syncC <-chan time.Time
+	if hub.backplane != nil {
+		syncC = r.requestSync()
+		ops = nil
+	}
+
+	// Una sala vacía espera idleGrace antes de cerrarse, con su documento, awareness y frames,
+	// por si alguien se reconecta
+	var idleTimer *time.Timer
+	var idleC <-chan time.Time
+
+	// El guardado se programa con cada edición y se posterga mientras sigan llegando
+	var persistTimer *time.Timer
+	var persistC <-chan time.Time
+
+	// Los cambios de awareness se juntan durante awarenessThrottle antes de enviarse
+	var awarenessC <-chan time.Time
+	awarenessSweep := time.NewTicker(awarenessSweepPeriod)
+
+	schedulePersist := func() {
+		if persistTimer == nil {
+			persistTimer = time.NewTimer(r.persistDue())
+		} else {
+			persistTimer.Reset(r.persistDue())
+		}
+		persistC = persistTimer.C
+	}
+
 	defer func() {
+		awarenessSweep.Stop()
+
+		// Guardar lo que quede pendiente antes de cerrar la sala
+		if persistTimer != nil {
+			persistTimer.Stop()
+		}
+		r.persist(hub)
+		// Las demás instancias dejan de contar con esta para guardar
+		r.publish(kindClosed, struct{}{})
+
 		// Limpiar canales al finalizar
 		r.mutex.Lock()
 		for client := range r.Clients {
 			close(client.send)
 		}
+		for _, client := range r.waiting {
+			close(client.send)
+		}
 		r.mutex.Unlock()
+		close(r.closed)
 
-		// Cerrar canales de la sala
-		close(r.Broadcast)
-		close(r.Register)
-		close(r.Unregister)
 	}()
 
 	for {
+		// Desconectar a los clientes que no pudieron recibir frames en la vuelta anterior
+		r.evictPending(hub)
+
+		switch empty := r.empty(); {
+		case empty && idleC == nil:
+			idleTimer = time.NewTimer(hub.idleGrace)
+			idleC = idleTimer.C
+		case !empty && idleC != nil:
+			idleTimer.Stop()
+			idleC = nil
+		}
+
 		select {
 		case client := <-r.Register:
-			r.mutex.Lock()
-			// Verificar si la sala está llena
-			if len(r.Clients) >= r.MaxUsers {
-				r.mutex.Unlock()
-				// Enviar mensaje de sala llena y cerrar conexión
-				message := Message{
-					Type: "error",
-					Data: "La sala está llena. Máximo 4 usuarios.",
-				}
-				if jsonMessage, err := json.Marshal(message); err == nil {
-					select {
-					case client.send <- jsonMessage:
-					default:
-						close(client.send)
-					}
-				}
-				continue
-			}
+			r.admit(hub, client)
 
-			r.Clients[client] = true
-			usersCount := len(r.Clients)
-			r.mutex.Unlock()
-
-			// Notificar que un usuario se unió
-			message := Message{
-				Type:      "user_joined",
-				ProjectID: r.ID,
-				UserID:    client.UserID,
-				Username:  client.Username,
-				Data: map[string]interface{}{
-					"message":     client.Username + " se unió a la sala",
-					"users_count": usersCount,
-					"users":       r.GetConnectedUsers(),
-				},
+		case client := <-r.Unregister:
+			if !r.leaveQueue(client) {
+				r.leave(hub, client)
 			}
 
+		case message := <-r.Broadcast:
 			r.broadcastMessage(message)
-			log.Printf("Cliente %s conectado a la sala %s. Usuarios conectados: %d",
-				client.UserID, r.ID, usersCount)
 
-		case client := <-r.Unregister:
-			r.mutex.Lock()
-			if _, ok := r.Clients[client]; ok {
-				delete(r.Clients, client)
-				close(client.send)
-				usersCount := len(r.Clients)
-				r.mutex.Unlock()
-
-				// Notificar que un usuario se desconectó
-				if usersCount > 0 { // Solo notificar si quedan usuarios
-					message := Message{
-						Type:      "user_left",
-						ProjectID: r.ID,
-						UserID:    client.UserID,
-						Username:  client.Username,
-						Data: map[string]interface{}{
-							"message":     client.Username + " dejó la sala",
-							"users_count": usersCount,
-							"users":       r.GetConnectedUsers(),
-						},
-					}
-					r.broadcastMessage(message)
-				}
+		case op := <-ops:
+			if !r.canEdit(op) {
+				continue
+			}
+			if hub.backplane != nil {
+				// Se aplica al volver por el backplane, en el mismo orden que en las demás instancias
+				r.publish(kindOp, op)
+				continue
+			}
+			if r.applyOp(op) {
+				schedulePersist()
+			}
 
-				log.Printf("Cliente %s desconectado de la sala %s. Usuarios conectados: %d",
-					client.UserID, r.ID, usersCount)
+		case envelope := <-r.remote:
+			if r.handleRemote(envelope) {
+				schedulePersist()
+			}
+			if r.removed() {
+				// Otra instancia avisó que se eliminó el proyecto
+				return
+			}
+			if ops == nil && !r.syncing() {
+				ops, syncC = r.ops, nil
+			}
 
-				// Si no quedan usuarios, programar eliminación de la sala
-				if usersCount == 0 {
-					go func() {
-						// Esperar un poco antes de eliminar la sala por si alguien se reconecta
-						// time.Sleep(30 * time.Second)
-						hub.RemoveRoom(r.ID)
-					}()
-				}
-			} else {
-				r.mutex.Unlock()
+		case <-r.gap:
+			syncC = r.resync()
+			ops = nil
+
+		case <-syncC:
+			// Ninguna instancia tenía la sala abierta: vale el documento de la base
+			if r.finishSync(nil) {
+				schedulePersist()
 			}
+			ops, syncC = r.ops, nil
 
-		case message := <-r.Broadcast:
-			r.mutex.RLock()
-			clientsToSend := make([]*Client, 0, len(r.Clients))
-			for client := range r.Clients {
-				clientsToSend = append(clientsToSend, client)
+		case <-r.awarenessSignal:
+			if awarenessC == nil {
+				awarenessC = time.After(awarenessThrottle)
 			}
-			r.mutex.RUnlock()
 
-			log.Printf("Broadcasting to %d clients in room %s", len(clientsToSend), r.ID)
+		case <-awarenessC:
+			awarenessC = nil
+			r.flushAwareness()
+
+		case <-awarenessSweep.C:
+			r.expireAwareness()
+			r.heartbeat()
+			r.checkLagging()
+			if r.document.dirty && persistC == nil && r.writer() {
+				// Esta instancia pasó a guardar el documento porque otra dejó de dar noticias
+				schedulePersist()
+			}
 
-			// Enviar mensaje a todos los clientes
-			var disconnectedClients []*Client
-			for _, client := range clientsToSend {
-				select {
-				case client.send <- message:
-					log.Printf("Message sent to client %s", client.UserID)
-				default:
-					// Cliente no puede recibir mensajes, marcar para desconectar
-					log.Printf("Client %s channel full, marking for disconnect", client.UserID)
-					disconnectedClients = append(disconnectedClients, client)
-				}
+		case d := <-r.direct:
+			if d.client != nil {
+				r.sendTo(d.client, d.message)
+			} else {
+				r.sendToUsers(d.userIDs, d.message)
 			}
 
-			// Limpiar clientes desconectados
-			if len(disconnectedClients) > 0 {
-				r.mutex.Lock()
-				for _, client := range disconnectedClients {
-					if _, ok := r.Clients[client]; ok {
-						close(client.send)
-						delete(r.Clients, client)
-						log.Printf("Removed disconnected client %s", client.UserID)
-					}
-				}
-				r.mutex.Unlock()
+		case action := <-r.moderation:
+			r.applyModeration(hub, action)
+
+		case <-persistC:
+			persistC = nil
+			r.persist(hub)
+
+		case change := <-r.project:
+			if r.applyProjectChange(hub, change) {
+				return
 			}
 
-		case <-r.done:
-			// Sala marcada para eliminación
+		case <-idleC:
+			// Nadie volvió durante la espera
+			hub.removeRoom(r)
+			return
+
+		case <-r.stop:
+			r.shutdown(hub)
 			return
 		}
 	}
 }
 
-// broadcastMessage envía un mensaje a todos los clientes de la sala
-func (r *Room) broadcastMessage(message Message) {
-	jsonMessage, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+// leave saca al cliente de la sala y avisa a los demás. Si no quedan usuarios guarda el
+// documento y programa la eliminación de la sala.
+func (r *Room) leave(hub *Hub, client *Client) {
+	r.mutex.Lock()
+	if _, ok := r.Clients[client]; !ok {
+		r.mutex.Unlock()
 		return
 	}
+	delete(r.Clients, client)
+	r.removePresence(client)
+	close(client.send)
+	usersCount := len(r.Clients)
+	r.mutex.Unlock()
+
+	// Notificar que un usuario se desconectó
+	if usersCount > 0 { // Solo notificar si quedan usuarios
+		message := Message{
+			Type:      "user_left",
+			ProjectID: r.ID,
+			UserID:    client.UserID,
+			Username:  client.Username,
+			Data: map[string]interface{}{
+				"message":     client.Username + " dejó la sala",
+				"users_count": usersCount,
+				"users":       r.GetConnectedUsers(),
+			},
+		}
+		r.broadcastMessage(message)
+	}
 
-	// Usar select con default para evitar bloqueos
-	select {
-	case r.Broadcast <- jsonMessage:
-	default:
-		log.Printf("Broadcast channel full, dropping message")
+	// El lugar que quedó libre pasa al primero de la cola
+	if !client.Spectator {
+		r.admitWaiting(hub)
+	}
+
+	log.Printf("Cliente %s desconectado de la sala %s. Usuarios conectados: %d",
+		client.UserID, r.ID, usersCount)
+
+	hub.events.Publish(event.Event{
+		Type:      event.RoomUserLeft,
+		ProjectID: r.ID,
+		UserID:    client.UserID,
+		Data: map[string]interface{}{
+			"username":    client.Username,
+			"users_count": usersCount,
+		},
+	})
+
+	// Si no quedan usuarios, guardar ya para que otra instancia cargue el último estado; run
+	// cierra la sala si sigue vacía después de idleGrace
+	if len(r.Clients) == 0 {
+		r.persist(hub)
 	}
 }
 
-// GetConnectedUsers retorna la lista de usuarios conectados en la sala
-func (r *Room) GetConnectedUsers() []map[string]string {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+// empty indica si la sala no tiene clientes conectados ni en espera. Solo se usa desde run.
+func (r *Room) empty() bool {
+	return len(r.Clients) == 0 && len(r.waiting) == 0
+}
 
-	users := make([]map[string]string, 0, len(r.Clients))
-	for client := range r.Clients {
-		users = append(users, map[string]string{
-			"user_id":  client.UserID,
-			"username": client.Username,
-		})
+// join agrega el cliente a la sala, avisa a los demás y le envía el estado actual
+func (r *Room) join(hub *Hub, client *Client) {
+	r.mutex.Lock()
+	r.Clients[client] = true
+	r.addPresence(client)
+	usersCount := len(r.Clients)
+	r.mutex.Unlock()
+	client.admitted.Store(true)
+
+	// Un cliente que reanuda recibe primero lo que se perdió
+	resumed := client.resumeFrom != nil && r.resume(client, *client.resumeFrom)
+
+	// Notificar que un usuario se unió
+	message := Message{
+		Type:      "user_joined",
+		ProjectID: r.ID,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		Data: map[string]interface{}{
+			"message":     client.Username + " se unió a la sala",
+			"users_count": usersCount,
+			"users":       r.GetConnectedUsers(),
+		},
 	}
-	return users
+
+	r.broadcastMessage(message)
+	if !resumed {
+		r.sendChatHistory(client)
+		r.sendDocument(client)
+	}
+	r.sendAwarenessSnapshot(client)
+	log.Printf("Cliente %s conectado a la sala %s. Usuarios conectados: %d",
+		client.UserID, r.ID, usersCount)
+
+	hub.events.Publish(event.Event{
+		Type:      event.RoomUserJoined,
+		ProjectID: r.ID,
+		UserID:    client.UserID,
+		Data: map[string]interface{}{
+			"username":    client.Username,
+			"users_count": usersCount,
+		},
+	})
 }
 
-// BroadcastToRoom envía un mensaje a todos los clientes de la sala
-func (r *Room) BroadcastToRoom(message Message) error {
-	jsonMessage, err := json.Marshal(message)
+// broadcastMessage envía el mensaje a todos los clientes de la sala, también a los conectados
+// a otras instancias. Solo se usa desde run, así los mensajes de la sala llegan en el mismo
+// orden que los dirigidos a un cliente.
+func (r *Room) broadcastMessage(message Message) {
+	r.broadcastLocal(message)
+	r.publish(kindFrame, message)
+}
+
+// broadcastLocal numera el mensaje, lo guarda para reanudar sesiones y lo envía a los
+// clientes conectados a esta instancia
+func (r *Room) broadcastLocal(message Message) {
+	// Se serializa una sola vez para todos los clientes
+	message.Seq = r.frames.next()
+	frame, err := newFrame(message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
-		return err
+		return
 	}
+	r.frames.record(message.Seq, frame)
+	r.deliver(frame, framePriority(message.Type))
+}
 
-	log.Printf("Sending message to broadcast channel for room %s, type: %s", r.ID, message.Type)
+// deliver escribe el mensaje en el canal de cada cliente según su prioridad. Los que no
+// pueden recibirlo se desconectan al terminar el evento en curso.
+func (r *Room) deliver(message *frame, priority framePriorityLevel) {
+	r.mutex.RLock()
+	clientsToSend := make([]*Client, 0, len(r.Clients))
+	for client := range r.Clients {
+		clientsToSend = append(clientsToSend, client)
+	}
+	r.mutex.RUnlock()
+
+	for _, client := range clientsToSend {
+		r.enqueue(client, message, priority)
+	}
+}
 
+// Reply envía un mensaje solo a un cliente de la sala. Pasa por la goroutine de la sala
+// para no escribir en el canal de un cliente que ya salió.
+func (r *Room) Reply(client *Client, message Message) {
 	select {
-	case r.Broadcast <- jsonMessage:
-		log.Printf("Message successfully queued for broadcast in room %s", r.ID)
+	case r.direct <- directMessage{client: client, message: message}:
+	case <-r.done:
+	}
+}
+
+// BroadcastToRoom envía un mensaje a todos los clientes de la sala. Los mensajes de chat
+// se guardan en el historial del proyecto antes de enviarse, y se borran si la sala no los
+// recibe para que el reintento del remitente no los duplique.
+func (r *Room) BroadcastToRoom(message Message) error {
+	if message.Type == MessageChat {
+		if err := r.storeChat(&message); err != nil {
+			log.Printf("Error guardando el mensaje de chat de la sala %s: %v", r.ID, err)
+			return err
+		}
+	}
+
+	var err error
+	select {
+	case r.Broadcast <- message:
 		return nil
+	case <-r.done:
+		err = ErrRoomClosed
 	default:
-		log.Printf("Broadcast channel full for room %s, message dropped", r.ID)
-		return nil
+		r.droppedFrames.Add(1)
+		log.Printf("Broadcast channel full for room %s, message %s dropped", r.ID, message.Type)
+		err = ErrRoomBusy
 	}
+	r.discardChat(message)
+	return err
 }
@@ -0,0 +1,56 @@
+package socket
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+)
+
+// Conexiones que entran y salen mientras la sala vacía vence su idleGrace: cada una entra a
+// una sala abierta, la sala le cierra el canal al salir y al final no queda ninguna sala
+func TestRegisterDuringIdleGrace(t *testing.T) {
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	hub := NewHub(Dependencies{
+		Events:    event.NewBus(),
+		Projects:  &stubProjects{name: "node", store: store},
+		Capacity:  Capacity{Editors: 4},
+		IdleGrace: time.Millisecond,
+	})
+	go hub.Run()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c := connectTestClient(hub, fmt.Sprintf("user-%d", i))
+				if _, ok := c.await(ofType(MessageDocument)); !ok {
+					t.Errorf("%s no entró a la sala en la vuelta %d", c.UserID, j)
+					return
+				}
+				hub.unregister <- c.Client
+				select {
+				case <-c.closed:
+				case <-time.After(5 * time.Second):
+					t.Errorf("la sala no cerró el canal de %s en la vuelta %d", c.UserID, j)
+					return
+				}
+				// A veces vuelve cuando la sala vacía está por cerrarse o ya se cerró
+				time.Sleep(time.Duration(j%4) * time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.GetRoom(testProjectID) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("la sala vacía no se cerró después de idleGrace")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
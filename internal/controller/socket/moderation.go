@@ -0,0 +1,123 @@
+package socket
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Tipos de mensaje de moderación, enviados solo al usuario moderado
+const (
+	MessageMuted   = "muted"   // No puede enviar chat ni ediciones hasta expires_at
+	MessageUnmuted = "unmuted" // Se levantó el silencio
+	MessageKicked  = "kicked"  // Fue desconectado de la sala
+	MessageBanned  = "banned"  // Fue desconectado y no puede volver hasta expires_at
+)
+
+const ErrorCodeMuted = "muted"
+
+// moderationAction es una acción de moderación sobre todas las conexiones de un usuario.
+// Se aplica en la sala de cada instancia.
+type moderationAction struct {
+	Action    string     `json:"action"` // entity.ModerationMute, ModerationUnmute, ModerationKick o ModerationBan
+	UserID    string     `json:"user_id"`
+	ActorID   string     `json:"actor_id"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// mute silencia la conexión hasta expiresAt; nil la silencia hasta que se levante
+func (c *Client) mute(expiresAt *time.Time) {
+	if expiresAt == nil {
+		c.mutedUntil.Store(math.MaxInt64)
+		return
+	}
+	c.mutedUntil.Store(expiresAt.UnixNano())
+}
+
+func (c *Client) muted() bool {
+	return time.Now().UnixNano() < c.mutedUntil.Load()
+}
+
+// sanction retorna la sanción vigente del usuario, o nil si no tiene o no hay moderación
+func (h *Hub) sanction(projectID string, userID uuid.UUID, kind string) (*entity.RoomSanction, error) {
+	if h.moderation == nil {
+		return nil, nil
+	}
+	return h.moderation.ActiveSanction(projectID, userID, kind)
+}
+
+// moderate aplica la acción en la sala local y la publica para las demás instancias
+func (h *Hub) moderate(projectID string, action moderationAction) {
+	if room := h.GetRoom(projectID); room != nil {
+		select {
+		case room.moderation <- action:
+		case <-room.done:
+		}
+	}
+	h.publish(projectID, kindModeration, action)
+}
+
+// applyModeration aplica la acción a las conexiones del usuario en esta instancia. Solo se usa desde run.
+func (r *Room) applyModeration(hub *Hub, action moderationAction) {
+	r.mutex.RLock()
+	var targets []*Client
+	for client := range r.Clients {
+		if client.UserID == action.UserID {
+			targets = append(targets, client)
+		}
+	}
+	for _, client := range r.waiting {
+		if client.UserID == action.UserID {
+			targets = append(targets, client)
+		}
+	}
+	r.mutex.RUnlock()
+
+	notice := Message{
+		Type:      moderationMessageType(action.Action),
+		ProjectID: r.ID,
+		UserID:    action.UserID,
+		Data: map[string]interface{}{
+			"reason":     action.Reason,
+			"expires_at": action.ExpiresAt,
+			"by":         action.ActorID,
+		},
+	}
+
+	for _, client := range targets {
+		r.sendTo(client, notice)
+		switch action.Action {
+		case entity.ModerationMute:
+			client.mute(action.ExpiresAt)
+		case entity.ModerationUnmute:
+			client.mutedUntil.Store(0)
+		case entity.ModerationKick, entity.ModerationBan:
+			client.closeCode = websocket.ClosePolicyViolation
+			client.closeText = "expulsado de la sala"
+			if !r.leaveQueue(client) {
+				r.leave(hub, client)
+			}
+		}
+	}
+	if len(targets) > 0 {
+		log.Printf("Moderación %s sobre %s en la sala %s (%d conexiones)", action.Action, action.UserID, r.ID, len(targets))
+	}
+}
+
+func moderationMessageType(action string) string {
+	switch action {
+	case entity.ModerationMute:
+		return MessageMuted
+	case entity.ModerationUnmute:
+		return MessageUnmuted
+	case entity.ModerationBan:
+		return MessageBanned
+	default:
+		return MessageKicked
+	}
+}
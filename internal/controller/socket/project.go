@@ -0,0 +1,236 @@
+package socket
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+// Tipos de mensaje de los cambios hechos al proyecto fuera de la sala
+const (
+	MessageProjectUpdated = "project_updated" // El proyecto se modificó por la API REST
+	MessageProjectDeleted = "project_deleted" // El proyecto se eliminó; la sala se cierra
+	MessageEditsDiscarded = "edits_discarded" // Ediciones sin guardar que no se pudieron aplicar sobre el Content recargado
+)
+
+// CloseProjectDeleted es el código de cierre de las conexiones de un proyecto eliminado
+const CloseProjectDeleted = 4404
+
+// projectChange es un cambio del proyecto que se avisa a la sala de cada instancia
+type projectChange struct {
+	Type      string   `json:"type"` // event.ProjectUpdated o event.ProjectDeleted
+	UserID    string   `json:"user_id,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Revision  int      `json:"revision,omitempty"`
+	Changed   []string `json:"changed,omitempty"`
+	Pointer   string   `json:"pointer,omitempty"`
+	Operation string   `json:"operation,omitempty"`
+}
+
+// documentReload es el Content que reemplaza al documento en vivo de la sala
+type documentReload struct {
+	Revision int                    `json:"revision"`
+	Content  map[string]interface{} `json:"content"`
+}
+
+// projectEvent recibe los eventos del bus y avisa a la sala del proyecto los cambios que no
+// hizo ella misma
+func (h *Hub) projectEvent(e event.Event) {
+	if e.Type != event.ProjectUpdated && e.Type != event.ProjectDeleted {
+		return
+	}
+	change := projectChange{Type: e.Type, UserID: e.UserID}
+	if data, ok := e.Data.(map[string]interface{}); ok {
+		if data["source"] == "room" {
+			// El guardado de la propia sala
+			return
+		}
+		change.Title, _ = data["title"].(string)
+		change.Revision, _ = data["revision"].(int)
+		change.Changed, _ = data["changed"].([]string)
+		change.Pointer, _ = data["pointer"].(string)
+		change.Operation, _ = data["operation"].(string)
+	}
+
+	if room := h.GetRoom(e.ProjectID); room != nil {
+		select {
+		case room.project <- change:
+		case <-room.done:
+		}
+	}
+	h.publish(e.ProjectID, kindProject, change)
+}
+
+// applyProjectChange avisa el cambio a los clientes de esta instancia y, si cambió el
+// Content, recarga el documento. Si el proyecto se eliminó, cierra la sala sin guardar el
+// documento. Retorna si la sala se cerró; solo se usa desde run.
+func (r *Room) applyProjectChange(hub *Hub, change projectChange) bool {
+	if change.Type == event.ProjectUpdated {
+		if slices.Contains(change.Changed, "content") {
+			r.reloadDocument(hub)
+		} else if change.Revision == r.document.revision+1 {
+			// El Content sigue igual: la sala puede guardar sobre la revisión nueva. Si falta
+			// una revisión intermedia el guardado choca y se recarga.
+			r.document.revision = change.Revision
+		}
+		data := map[string]interface{}{
+			"revision": change.Revision,
+			"title":    change.Title,
+			"changed":  change.Changed,
+		}
+		if change.Pointer != "" {
+			data["pointer"] = change.Pointer
+			data["operation"] = change.Operation
+		}
+		r.broadcastLocal(Message{
+			Type:      MessageProjectUpdated,
+			ProjectID: r.ID,
+			UserID:    change.UserID,
+			Data:      data,
+		})
+		return false
+	}
+
+	r.mutex.RLock()
+	clients := make([]*Client, 0, len(r.Clients)+len(r.waiting))
+	for client := range r.Clients {
+		clients = append(clients, client)
+	}
+	clients = append(clients, r.waiting...)
+	r.mutex.RUnlock()
+
+	deleted := Message{
+		Type:      MessageProjectDeleted,
+		ProjectID: r.ID,
+		UserID:    change.UserID,
+		Data: map[string]interface{}{
+			"message": "El proyecto fue eliminado",
+		},
+	}
+	for _, client := range clients {
+		r.sendTo(client, deleted)
+		client.closeCode = CloseProjectDeleted
+		client.closeText = "proyecto eliminado"
+	}
+	// Guardar el documento volvería a escribir el proyecto eliminado
+	r.document.dirty = false
+	log.Printf("Sala %s cerrada porque se eliminó el proyecto (%d clientes)", r.ID, len(clients))
+	hub.removeRoom(r)
+	return true
+}
+
+// reloadDocument lee el Content de la base si cambió desde la revisión que conoce la sala.
+// Lo lee solo la instancia que guarda y lo reparte como una operación más, así todas
+// reemplazan el documento en el mismo punto del orden de operaciones.
+func (r *Room) reloadDocument(hub *Hub) {
+	if !r.document.loaded || !r.writer() {
+		return
+	}
+	project, err := hub.projects.GetProjectByID(r.ID)
+	if err != nil {
+		log.Printf("Error recargando el documento de la sala %s: %v", r.ID, err)
+		return
+	}
+	if project.Revision <= r.document.revision {
+		return
+	}
+	doc, err := content.Parse(project.Content)
+	if err != nil {
+		log.Printf("Documento inválido en la sala %s: %v", r.ID, err)
+		return
+	}
+
+	reload := documentReload{Revision: project.Revision, Content: doc}
+	if hub.backplane != nil {
+		r.publish(kindOp, clientOp{Reload: &reload})
+		return
+	}
+	r.replaceDocument(reload)
+}
+
+// replaceDocument reemplaza el documento en vivo y se lo envía a los clientes. Las ediciones
+// sin guardar se vuelven a aplicar sobre el Content nuevo; las que ya no se pueden aplicar se
+// descartan y se avisa a la sala. Las operaciones en vuelo ya no tienen base y sus clientes
+// reciben el documento, y los historiales se vacían porque sus inversas ya no valen.
+func (r *Room) replaceDocument(reload documentReload) {
+	if reload.Revision <= r.document.revision {
+		return
+	}
+	unsaved, complete := r.unsavedOps()
+	discarded := 0
+	if !complete {
+		// El log ya no tiene todas: aplicar solo algunas dejaría un documento inconsistente
+		discarded = int(r.document.seq - r.document.savedSeq)
+		unsaved = nil
+	}
+
+	r.document.content = reload.Content
+	r.document.revision = reload.Revision
+	r.document.seq++
+	r.document.savedSeq = r.document.seq
+	r.document.log = nil
+	r.document.history = nil
+	r.document.loaded = true
+	r.document.dirty = false
+
+	reapplied := 0
+	for _, operation := range unsaved {
+		effect, err := content.Apply(r.document.content, operation)
+		if err != nil {
+			discarded++
+			continue
+		}
+		reapplied++
+		r.document.seq++
+		r.document.log = append(r.document.log, appliedOp{Seq: r.document.seq, Op: operation, Effect: effect})
+		if !r.document.dirty {
+			r.document.dirty = true
+			r.document.dirtySince = time.Now()
+		}
+	}
+
+	r.mutex.RLock()
+	clients := make([]*Client, 0, len(r.Clients))
+	for client := range r.Clients {
+		clients = append(clients, client)
+	}
+	r.mutex.RUnlock()
+
+	for _, client := range clients {
+		r.sendDocument(client)
+		r.sendHistory(clientOp{client: client, UserID: client.UserID})
+	}
+	if discarded > 0 {
+		r.broadcastLocal(Message{
+			Type:      MessageEditsDiscarded,
+			ProjectID: r.ID,
+			Data: map[string]interface{}{
+				"revision":  reload.Revision,
+				"discarded": discarded,
+			},
+		})
+	}
+	log.Printf("Documento de la sala %s recargado (revisión %d, operación %d, %d ediciones reaplicadas, %d descartadas)",
+		r.ID, reload.Revision, r.document.seq, reapplied, discarded)
+}
+
+// unsavedOps retorna las operaciones aplicadas después del último guardado, en orden, y si
+// el log todavía las tiene todas
+func (r *Room) unsavedOps() ([]content.Operation, bool) {
+	if r.document.seq <= r.document.savedSeq {
+		return nil, true
+	}
+	if len(r.document.log) == 0 || r.document.log[0].Seq > r.document.savedSeq+1 {
+		return nil, false
+	}
+	var operations []content.Operation
+	for _, applied := range r.document.log {
+		if applied.Seq > r.document.savedSeq {
+			operations = append(operations, applied.Op)
+		}
+	}
+	return operations, true
+}
@@ -0,0 +1,203 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/backplane"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+type documentData struct {
+	Seq     int64                  `json:"seq"`
+	Content map[string]interface{} `json:"content"`
+}
+
+func insertRequest(base int64, nodeID string) editRequest {
+	return editRequest{
+		BaseSeq: &base,
+		Operation: content.Operation{
+			Type:     content.OpInsertNode,
+			NodeID:   nodeID,
+			ParentID: content.RootID,
+			Node:     map[string]interface{}{content.TypeKey: "Text"},
+		},
+	}
+}
+
+// Un cambio del Content por la API reemplaza el documento en vivo con las ediciones sin
+// guardar aplicadas encima, y las operaciones creadas sobre el anterior se rechazan
+func TestRoomReloadsContentChangedOutside(t *testing.T) {
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	hub := newTestHub("node", store, nil)
+	alice := connectTestClient(hub, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+	room := hub.GetRoom(testProjectID)
+
+	room.SubmitOp(alice.Client, insertRequest(0, "a"))
+	alice.waitFor(t, "la operación", ofType(MessageOp))
+
+	store.write(`{"id":"root","children":[{"id":"rest","type":"Text"}]}`)
+	hub.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: testProjectID,
+		Data:      map[string]interface{}{"changed": []string{"content"}, "revision": 2},
+	})
+
+	var reloaded documentData
+	json.Unmarshal(alice.waitFor(t, "el documento recargado", ofType(MessageDocument)).Data, &reloaded)
+	want := map[string]interface{}{"id": "root", "children": []interface{}{
+		map[string]interface{}{"id": "a", "type": "Text"},
+		map[string]interface{}{"id": "rest", "type": "Text"},
+	}}
+	if reloaded.Seq != 3 || !reflect.DeepEqual(reloaded.Content, want) {
+		t.Fatalf("se esperaba el documento de la base con la edición sin guardar en la operación 3, se obtuvo %+v", reloaded)
+	}
+
+	// Creada sobre el documento anterior a la recarga
+	room.SubmitOp(alice.Client, insertRequest(1, "b"))
+	alice.waitFor(t, "el rechazo", ofType(MessageOpRejected))
+
+	room.SubmitOp(alice.Client, insertRequest(3, "c"))
+	alice.waitFor(t, "la operación", ofType(MessageOp))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	saved, _ := content.Parse(store.content)
+	if got := childOrder(saved); !reflect.DeepEqual(got, []string{"c", "a", "rest"}) {
+		t.Fatalf("se esperaba guardar [c a rest], se guardó %v", got)
+	}
+}
+
+// Las ediciones sin guardar que no se pueden aplicar sobre el Content recargado se avisan
+func TestRoomReportsDiscardedEdits(t *testing.T) {
+	store := newProjectStore(`{"id":"root","children":[{"id":"x","type":"Text"}]}`)
+	hub := newTestHub("node", store, nil)
+	alice := connectTestClient(hub, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+
+	base := int64(0)
+	hub.GetRoom(testProjectID).SubmitOp(alice.Client, editRequest{
+		BaseSeq:   &base,
+		Operation: content.Operation{Type: content.OpSetProp, NodeID: "x", Key: "text", Value: "hola"},
+	})
+	alice.waitFor(t, "la operación", ofType(MessageOp))
+
+	// La API elimina el nodo editado
+	store.write(`{"id":"root","children":[]}`)
+	hub.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: testProjectID,
+		Data:      map[string]interface{}{"changed": []string{"content"}, "revision": 2},
+	})
+
+	var discarded struct {
+		Revision  int `json:"revision"`
+		Discarded int `json:"discarded"`
+	}
+	json.Unmarshal(alice.waitFor(t, "el aviso", ofType(MessageEditsDiscarded)).Data, &discarded)
+	if discarded.Revision != 2 || discarded.Discarded != 1 {
+		t.Fatalf("se esperaba una edición descartada en la revisión 2, se obtuvo %+v", discarded)
+	}
+}
+
+// Un cambio que no toca el Content avanza la revisión de la sala, que sigue guardando sus
+// ediciones sin recargar
+func TestRoomKeepsSavingAfterTitleChange(t *testing.T) {
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	hub := newTestHub("node", store, nil)
+	alice := connectTestClient(hub, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+
+	hub.GetRoom(testProjectID).SubmitOp(alice.Client, insertRequest(0, "a"))
+	alice.waitFor(t, "la operación", ofType(MessageOp))
+
+	store.touch()
+	hub.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: testProjectID,
+		Data:      map[string]interface{}{"changed": []string{"title"}, "title": "Nuevo", "revision": 2},
+	})
+	alice.waitFor(t, "el aviso del cambio", ofType(MessageProjectUpdated))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	saved, _ := content.Parse(store.content)
+	if got := childOrder(saved); store.saves["node"] != 1 || !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("se esperaba guardar [a] sobre la revisión 2, se guardó %v (%d guardados)", got, store.saves["node"])
+	}
+}
+
+// La sala no guarda sobre una revisión que no conoce aunque todavía no le llegó el evento
+func TestRoomDoesNotOverwriteNewerRevision(t *testing.T) {
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	hub := newTestHub("node", store, nil)
+	alice := connectTestClient(hub, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+
+	hub.GetRoom(testProjectID).SubmitOp(alice.Client, insertRequest(0, "a"))
+	alice.waitFor(t, "la operación", ofType(MessageOp))
+
+	const external = `{"id":"root","children":[{"id":"rest"}]}`
+	store.write(external)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if string(store.content) != external || len(store.saves) != 0 {
+		t.Fatalf("la sala pisó el cambio externo: %s", store.content)
+	}
+}
+
+// Con varias instancias lee la base solo la que guarda, y todas recargan en la misma operación
+func TestHubsReloadContentTogether(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	store := newProjectStore(`{"id":"root","children":[]}`)
+	a := newTestHub("node-a", store, bp)
+	b := newTestHub("node-b", store, bp)
+
+	alice := connectTestClient(b, "alice")
+	alice.waitFor(t, "el documento", ofType(MessageDocument))
+	bob := connectTestClient(a, "bob")
+	bob.waitFor(t, "el documento", ofType(MessageDocument))
+	alice.waitFor(t, "la presencia de bob", sees(bob.ID))
+
+	// El cambio llega a la instancia que no guarda
+	store.write(`{"id":"root","children":[{"id":"rest"}]}`)
+	b.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: testProjectID,
+		Data:      map[string]interface{}{"changed": []string{"content"}, "revision": 2},
+	})
+
+	reloaded := func(c *testClient) documentData {
+		var data documentData
+		for len(data.Content) == 0 || len(childOrder(data.Content)) == 0 {
+			json.Unmarshal(c.waitFor(t, "el documento recargado", ofType(MessageDocument)).Data, &data)
+		}
+		return data
+	}
+	aliceDoc, bobDoc := reloaded(alice), reloaded(bob)
+	if !reflect.DeepEqual(aliceDoc, bobDoc) || aliceDoc.Seq != 1 {
+		t.Fatalf("las instancias recargaron distinto:\nalice %+v\nbob   %+v", aliceDoc, bobDoc)
+	}
+}
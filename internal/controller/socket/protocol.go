@@ -0,0 +1,355 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+// ProtocolVersion es la versión del catálogo de mensajes. Los clientes pueden enviarla en "v";
+// un mensaje de una versión posterior se rechaza.
+const ProtocolVersion = 1
+
+// Tipos de mensaje del catálogo, además de los de edición en vivo (document.go)
+const (
+	MessageCursor    = "cursor"    // Posición del puntero del usuario
+	MessageSelection = "selection" // Nodos seleccionados por el usuario
+	MessageChat      = "chat"      // Mensaje de chat para la sala
+	MessagePing      = "ping"      // Cliente -> servidor: latido a nivel de aplicación
+	MessagePong      = "pong"      // Servidor -> remitente: respuesta al ping
+	MessageError     = "error"     // Servidor -> remitente: el mensaje no se aceptó
+)
+
+// Códigos de los frames de error
+const (
+	ErrorCodeMalformed   = "malformed_message"
+	ErrorCodeUnknownType = "unknown_type"
+	ErrorCodeInvalid     = "invalid_payload"
+	ErrorCodeVersion     = "unsupported_version"
+	ErrorCodeRoomFull    = "room_full"
+	ErrorCodeNotFound    = "not_found"
+	ErrorCodeForbidden   = "forbidden"
+	ErrorCodeInternal    = "internal_error"
+	ErrorCodeWaiting     = "waiting_for_seat"
+	ErrorCodeBusy        = "room_busy"
+)
+
+const (
+	maxChatLength      = 2000
+	maxSelectedNodes   = 200
+	maxScreenNameBytes = 128
+)
+
+// incomingMessage es un frame recibido de un cliente antes de decodificar su payload
+type incomingMessage struct {
+	Type    string          `json:"type"`
+	Version int             `json:"v,omitempty"`
+	Data    json.RawMessage `json:"data"`
+	To      []string        `json:"to,omitempty"` // Usuarios destinatarios; solo en los tipos que lo admiten
+}
+
+// ErrorPayload es el contenido de un frame "error"
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"` // Tipo del mensaje rechazado
+	// RetryAfter es cuántos milisegundos esperar antes de reenviar un mensaje limitado
+	RetryAfter int64 `json:"retry_after_ms,omitempty"`
+}
+
+// payload es el contenido tipado de un mensaje del catálogo
+type payload interface {
+	Validate() error
+}
+
+type CursorPayload struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Screen string  `json:"screen,omitempty"`
+}
+
+func (p *CursorPayload) Validate() error {
+	if math.IsNaN(p.X) || math.IsInf(p.X, 0) || math.IsNaN(p.Y) || math.IsInf(p.Y, 0) {
+		return errors.New("x e y deben ser números finitos")
+	}
+	if len(p.Screen) > maxScreenNameBytes {
+		return errors.New("screen es demasiado largo")
+	}
+	return nil
+}
+
+type SelectionPayload struct {
+	NodeIDs []string `json:"node_ids"`
+}
+
+func (p *SelectionPayload) Validate() error {
+	if len(p.NodeIDs) > maxSelectedNodes {
+		return fmt.Errorf("no se pueden seleccionar más de %d nodos", maxSelectedNodes)
+	}
+	for _, id := range p.NodeIDs {
+		if id == "" {
+			return errors.New("node_ids no puede contener IDs vacíos")
+		}
+	}
+	return nil
+}
+
+type ChatPayload struct {
+	Text string `json:"text"`
+}
+
+func (p *ChatPayload) Validate() error {
+	p.Text = strings.TrimSpace(p.Text)
+	if p.Text == "" {
+		return errors.New("text es requerido")
+	}
+	if utf8.RuneCountInString(p.Text) > maxChatLength {
+		return fmt.Errorf("text no puede superar los %d caracteres", maxChatLength)
+	}
+	return nil
+}
+
+type PingPayload struct {
+	Timestamp int64 `json:"ts,omitempty"` // Se devuelve tal cual en el pong para medir latencia
+}
+
+func (p *PingPayload) Validate() error { return nil }
+
+func (r *editRequest) Validate() error {
+	if r.NodeID == "" {
+		return errors.New("node_id es requerido")
+	}
+	switch r.Type {
+	case content.OpInsertNode:
+		if r.Node == nil {
+			return errors.New("node es requerido")
+		}
+	case content.OpDeleteNode, content.OpMoveNode:
+	case content.OpSetProp:
+		if r.Key == "" {
+			return errors.New("key es requerido")
+		}
+	default:
+		return fmt.Errorf("tipo de operación desconocido: %q", r.Type)
+	}
+	return nil
+}
+
+// messageSpec registra un tipo de mensaje: cómo decodificar su payload y qué hacer con él
+type messageSpec struct {
+	payload func() payload
+	// handle procesa el mensaje sin "to"; nil si el tipo siempre es dirigido
+	handle func(room *Room, c *Client, p payload)
+	// direct procesa el mensaje dirigido a los usuarios de "to"; nil si el tipo no lo admite
+	direct func(room *Room, c *Client, p payload, to []string)
+	// relay indica que el mensaje se puede retransmitir a la sala, también desde la API REST
+	relay bool
+	// muteable indica que un usuario silenciado no lo puede enviar
+	muteable bool
+	// editors indica que solo lo envían quienes pueden editar, no los lectores ni los espectadores
+	editors bool
+}
+
+// registry es el catálogo de mensajes que aceptan los clientes
+var registry = map[string]messageSpec{
+	MessageOp: {
+		payload: func() payload { return &editRequest{} },
+		handle: func(room *Room, c *Client, p payload) {
+			room.SubmitOp(c, *p.(*editRequest))
+		},
+		muteable: true,
+	},
+	MessageUndo: {
+		payload: func() payload { return &HistoryRequest{} },
+		handle: func(room *Room, c *Client, p payload) {
+			room.SubmitHistory(c, MessageUndo, *p.(*HistoryRequest))
+		},
+		muteable: true,
+	},
+	MessageRedo: {
+		payload: func() payload { return &HistoryRequest{} },
+		handle: func(room *Room, c *Client, p payload) {
+			room.SubmitHistory(c, MessageRedo, *p.(*HistoryRequest))
+		},
+		muteable: true,
+	},
+	MessageCursor: {
+		payload: func() payload { return &CursorPayload{} },
+		handle: func(room *Room, c *Client, p payload) {
+			cursor := p.(*CursorPayload)
+			room.updatePresence(c, func(state *Presence) {
+				state.Pointer = &Pointer{X: cursor.X, Y: cursor.Y}
+				if cursor.Screen != "" {
+					state.Screen = cursor.Screen
+				}
+			})
+		},
+	},
+	MessageSelection: {
+		payload: func() payload { return &SelectionPayload{} },
+		handle: func(room *Room, c *Client, p payload) {
+			selection := p.(*SelectionPayload)
+			room.updatePresence(c, func(state *Presence) {
+				state.Selection = append([]string{}, selection.NodeIDs...)
+			})
+		},
+	},
+	MessageAwareness: {
+		payload: func() payload { return &AwarenessPayload{} },
+		handle: func(room *Room, c *Client, p payload) {
+			update := p.(*AwarenessPayload)
+			room.updatePresence(c, func(state *Presence) {
+				if update.Status != nil {
+					state.Status = *update.Status
+				}
+				if update.Screen != nil {
+					state.Screen = *update.Screen
+				}
+			})
+		},
+	},
+	MessageChat: {
+		payload:  func() payload { return &ChatPayload{} },
+		handle:   relayToRoom(MessageChat),
+		direct:   privateChat,
+		relay:    true,
+		muteable: true,
+	},
+	MessageChatEdit: {
+		payload:  func() payload { return &ChatEditPayload{} },
+		handle:   editChat,
+		muteable: true,
+	},
+	MessageChatDelete: {
+		payload:  func() payload { return &ChatDeletePayload{} },
+		handle:   deleteChat,
+		muteable: true,
+	},
+	MessageRPCRequest: {
+		payload:  func() payload { return &RPCRequestPayload{} },
+		direct:   relayToUsers(MessageRPCRequest),
+		muteable: true,
+		editors:  true,
+	},
+	MessageRPCResponse: {
+		payload:  func() payload { return &RPCResponsePayload{} },
+		direct:   relayToUsers(MessageRPCResponse),
+		muteable: true,
+		editors:  true,
+	},
+	MessagePing: {
+		payload: func() payload { return &PingPayload{} },
+		handle: func(room *Room, c *Client, p payload) {
+			room.Reply(c, Message{Type: MessagePong, ProjectID: room.ID, Data: p})
+		},
+	},
+}
+
+// relayToRoom retransmite el payload validado a toda la sala con la identidad del remitente
+func relayToRoom(messageType string) func(room *Room, c *Client, p payload) {
+	return func(room *Room, c *Client, p payload) {
+		err := room.BroadcastToRoom(Message{
+			Type:      messageType,
+			Data:      p,
+			ProjectID: c.ProjectID,
+			UserID:    c.UserID,
+			Username:  c.Username,
+		})
+		if err != nil {
+			room.Reply(c, broadcastError(room.ID, messageType, err))
+		}
+	}
+}
+
+// broadcastError avisa al remitente que su mensaje no llegó a la sala
+func broadcastError(projectID, messageType string, err error) Message {
+	if errors.Is(err, ErrRoomBusy) {
+		return errorMessage(projectID, &ErrorPayload{Code: ErrorCodeBusy, Message: err.Error(), Type: messageType})
+	}
+	return errorMessage(projectID, &ErrorPayload{Code: ErrorCodeInternal, Message: "no se pudo enviar el mensaje", Type: messageType})
+}
+
+// decodePayload busca el tipo en el catálogo y valida su contenido
+func decodePayload(messageType string, data json.RawMessage) (messageSpec, payload, *ErrorPayload) {
+	spec, ok := registry[messageType]
+	if !ok {
+		return spec, nil, &ErrorPayload{Code: ErrorCodeUnknownType, Message: "tipo de mensaje desconocido", Type: messageType}
+	}
+
+	p := spec.payload()
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, p); err != nil {
+			return spec, nil, &ErrorPayload{Code: ErrorCodeInvalid, Message: "data no tiene el formato esperado", Type: messageType}
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return spec, nil, &ErrorPayload{Code: ErrorCodeInvalid, Message: err.Error(), Type: messageType}
+	}
+	return spec, p, nil
+}
+
+// dispatch procesa un frame recibido del cliente. Lo que no está en el catálogo o no pasa
+// la validación se responde con un frame "error" solo al remitente. Retorna false si el
+// cliente debe desconectarse por seguir excediendo los límites de mensajes.
+func (c *Client) dispatch(raw []byte) bool {
+	room := c.hub.GetRoom(c.ProjectID)
+	if room == nil {
+		return true
+	}
+
+	var incoming incomingMessage
+	err := json.Unmarshal(raw, &incoming)
+	if allowed, keep := c.checkLimits(room, incoming.Type, len(raw)); !allowed {
+		return keep
+	}
+	if err != nil || incoming.Type == "" {
+		room.Reply(c, errorMessage(room.ID, &ErrorPayload{Code: ErrorCodeMalformed, Message: "el mensaje debe ser un objeto JSON con type"}))
+		return true
+	}
+	if incoming.Version > ProtocolVersion {
+		room.Reply(c, errorMessage(room.ID, &ErrorPayload{
+			Code:    ErrorCodeVersion,
+			Message: fmt.Sprintf("versión de protocolo no soportada, máximo %d", ProtocolVersion),
+			Type:    incoming.Type,
+		}))
+		return true
+	}
+
+	spec, p, errPayload := decodePayload(incoming.Type, incoming.Data)
+	if errPayload == nil && !c.admitted.Load() && incoming.Type != MessagePing {
+		errPayload = &ErrorPayload{Code: ErrorCodeWaiting, Message: "todavía estás en la cola de espera", Type: incoming.Type}
+	}
+	if errPayload == nil && spec.editors && (c.Spectator || c.Role == entity.ProjectRoleViewer) {
+		errPayload = &ErrorPayload{Code: ErrorCodeForbidden, Message: "los espectadores no pueden enviar este mensaje", Type: incoming.Type}
+	}
+	if errPayload == nil && spec.muteable && c.muted() {
+		errPayload = &ErrorPayload{Code: ErrorCodeMuted, Message: "un moderador te silenció en esta sala", Type: incoming.Type}
+	}
+	if errPayload == nil {
+		errPayload = room.checkRecipients(spec, &incoming)
+	}
+	if errPayload != nil {
+		room.Reply(c, errorMessage(room.ID, errPayload))
+		return true
+	}
+	if len(incoming.To) > 0 {
+		spec.direct(room, c, p, incoming.To)
+		return true
+	}
+	spec.handle(room, c, p)
+	return true
+}
+
+func errorMessage(projectID string, p *ErrorPayload) Message {
+	return Message{
+		Type:      MessageError,
+		ProjectID: projectID,
+		Data:      p,
+	}
+}
@@ -0,0 +1,250 @@
+package socket
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Escalamiento de los clientes que superan los límites
+const (
+	// noticeInterval es cada cuánto se avisa a un cliente que sus mensajes se descartan
+	noticeInterval = time.Second
+	// maxStrikes es cuántos avisos seguidos se toleran antes de desconectar al cliente
+	maxStrikes = 5
+	// strikeReset es el tiempo sin excesos tras el cual se olvidan los avisos
+	strikeReset = 10 * time.Second
+)
+
+// Códigos de los frames de error de los límites
+const (
+	ErrorCodeRateLimited = "rate_limited"
+	ErrorCodeTooLarge    = "message_too_large"
+)
+
+// MessageLimit es la política de un tipo de mensaje entrante: Rate mensajes por segundo con
+// ráfagas de hasta Burst, y frames de hasta MaxBytes. Rate 0 no limita la frecuencia y
+// MaxBytes 0 aplica el tamaño máximo de la conexión.
+type MessageLimit struct {
+	Rate     float64
+	Burst    int
+	MaxBytes int
+}
+
+// Limits son las políticas de los mensajes que envían los clientes
+type Limits struct {
+	Connection MessageLimit            // Todos los mensajes de una conexión; MaxBytes vale para los tipos sin tamaño propio
+	Types      map[string]MessageLimit // Por tipo de mensaje; los que no están solo usan Connection
+}
+
+// DefaultLimits deja margen a las operaciones, que pueden traer nodos completos, y ajusta el
+// resto al tamaño y la frecuencia que tienen en un cliente normal
+var DefaultLimits = Limits{
+	Connection: MessageLimit{Rate: 60, Burst: 120, MaxBytes: 64 * 1024},
+	Types: map[string]MessageLimit{
+		MessageOp:          {Rate: 20, Burst: 60, MaxBytes: 64 * 1024},
+		MessageCursor:      {Rate: 30, Burst: 30, MaxBytes: 512},
+		MessageSelection:   {Rate: 10, Burst: 20, MaxBytes: 16 * 1024},
+		MessageAwareness:   {Rate: 5, Burst: 10, MaxBytes: 512},
+		MessageChat:        {Rate: 2, Burst: 5, MaxBytes: 10 * 1024},
+		MessageChatEdit:    {Rate: 2, Burst: 5, MaxBytes: 10 * 1024},
+		MessageChatDelete:  {Rate: 2, Burst: 5, MaxBytes: 512},
+		MessageUndo:        {Rate: 10, Burst: 20, MaxBytes: 256},
+		MessageRedo:        {Rate: 10, Burst: 20, MaxBytes: 256},
+		MessageRPCRequest:  {Rate: 5, Burst: 10, MaxBytes: 8 * 1024},
+		MessageRPCResponse: {Rate: 5, Burst: 10, MaxBytes: 8 * 1024},
+		MessagePing:        {Rate: 1, Burst: 5, MaxBytes: 256},
+	},
+}
+
+// WithOverrides retorna los límites con los valores configurados reemplazando a los de cada
+// tipo. La clave "*" es la conexión.
+func (l Limits) WithOverrides(overrides map[string]MessageLimit) (Limits, error) {
+	merged := Limits{Connection: l.Connection, Types: make(map[string]MessageLimit, len(l.Types))}
+	for messageType, limit := range l.Types {
+		merged.Types[messageType] = limit
+	}
+	for messageType, limit := range overrides {
+		if messageType == "*" {
+			merged.Connection = limit
+			continue
+		}
+		if _, ok := registry[messageType]; !ok {
+			return merged, fmt.Errorf("tipo de mensaje desconocido: %q", messageType)
+		}
+		merged.Types[messageType] = limit
+	}
+	return merged, nil
+}
+
+// withDefaults usa DefaultLimits si no se configuró ningún límite
+func (l Limits) withDefaults() Limits {
+	if l.Types == nil && l.Connection == (MessageLimit{}) {
+		return DefaultLimits
+	}
+	return l
+}
+
+// readLimit es el frame más grande que se lee de una conexión: el mayor de todos los tamaños
+// máximos, así un frame que solo excede el de su tipo se descarta con message_too_large en
+// lugar de cerrar la conexión
+func (l Limits) readLimit() int64 {
+	limit := l.Connection.MaxBytes
+	for _, typeLimit := range l.Types {
+		limit = max(limit, typeLimit.MaxBytes)
+	}
+	return int64(limit)
+}
+
+// tokenBucket recarga Rate fichas por segundo hasta Burst; cada mensaje consume una
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit MessageLimit, now time.Time) *tokenBucket {
+	burst := float64(max(limit.Burst, 1))
+	return &tokenBucket{rate: limit.Rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if b.rate <= 0 {
+		return true
+	}
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryAfter es cuánto falta para la próxima ficha
+func (b *tokenBucket) retryAfter() time.Duration {
+	if b.rate <= 0 || b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// rateLimiter aplica los límites a los mensajes de un cliente. Solo lo usa su readPump.
+type rateLimiter struct {
+	limits     Limits
+	connection *tokenBucket
+	types      map[string]*tokenBucket
+
+	throttled  bool      // Ya se le descartó algún mensaje
+	strikes    int       // Avisos seguidos sin dejar de exceder los límites
+	lastNotice time.Time // Último aviso enviado
+	lastExcess time.Time // Último mensaje descartado
+}
+
+func newRateLimiter(limits Limits) *rateLimiter {
+	return &rateLimiter{
+		limits:     limits,
+		connection: newTokenBucket(limits.Connection, time.Now()),
+		types:      make(map[string]*tokenBucket),
+	}
+}
+
+// limitVerdict es lo que se hace con un mensaje según los límites
+type limitVerdict int
+
+const (
+	verdictAllow      limitVerdict = iota // Se procesa
+	verdictDrop                           // Se descarta sin avisar; ya se avisó hace poco
+	verdictNotice                         // Se descarta y se avisa al cliente
+	verdictDisconnect                     // Se descarta y se desconecta al cliente
+)
+
+// check decide qué hacer con un mensaje del tipo y tamaño dados. messageType vacío es un
+// mensaje que no se pudo leer; solo cuenta para el límite de la conexión.
+func (l *rateLimiter) check(messageType string, size int, now time.Time) (limitVerdict, *ErrorPayload) {
+	if !l.connection.allow(now) {
+		return l.excess(now, &ErrorPayload{
+			Code:       ErrorCodeRateLimited,
+			Message:    "demasiados mensajes, espera antes de enviar más",
+			Type:       messageType,
+			RetryAfter: l.connection.retryAfter().Milliseconds(),
+		})
+	}
+
+	limit, ok := l.limits.Types[messageType]
+	maxBytes := l.limits.Connection.MaxBytes
+	if limit.MaxBytes > 0 {
+		maxBytes = limit.MaxBytes
+	}
+	if maxBytes > 0 && size > maxBytes {
+		return l.excess(now, &ErrorPayload{
+			Code:    ErrorCodeTooLarge,
+			Message: fmt.Sprintf("el mensaje no puede superar los %d bytes", maxBytes),
+			Type:    messageType,
+		})
+	}
+	if !ok {
+		return verdictAllow, nil
+	}
+
+	bucket, ok := l.types[messageType]
+	if !ok {
+		bucket = newTokenBucket(limit, now)
+		l.types[messageType] = bucket
+	}
+	if !bucket.allow(now) {
+		return l.excess(now, &ErrorPayload{
+			Code:       ErrorCodeRateLimited,
+			Message:    "demasiados mensajes de este tipo, espera antes de enviar más",
+			Type:       messageType,
+			RetryAfter: bucket.retryAfter().Milliseconds(),
+		})
+	}
+	return verdictAllow, nil
+}
+
+// excess registra un mensaje descartado. Se avisa como mucho una vez por noticeInterval y,
+// si el cliente sigue excediendo los límites tras maxStrikes avisos, se lo desconecta.
+func (l *rateLimiter) excess(now time.Time, notice *ErrorPayload) (limitVerdict, *ErrorPayload) {
+	if now.Sub(l.lastExcess) > strikeReset {
+		l.strikes = 0
+	}
+	l.lastExcess = now
+
+	if now.Sub(l.lastNotice) < noticeInterval {
+		return verdictDrop, nil
+	}
+	l.lastNotice = now
+	l.strikes++
+	if l.strikes > maxStrikes {
+		return verdictDisconnect, notice
+	}
+	return verdictNotice, notice
+}
+
+// checkLimits aplica los límites al mensaje y lleva los contadores de la sala. Retorna si el
+// mensaje se procesa y, si no, si la conexión sigue abierta.
+func (c *Client) checkLimits(room *Room, messageType string, size int) (allowed, keep bool) {
+	verdict, notice := c.limiter.check(messageType, size, time.Now())
+	if verdict == verdictAllow {
+		return true, true
+	}
+
+	room.throttledMessages.Add(1)
+	if !c.limiter.throttled {
+		c.limiter.throttled = true
+		room.throttledClients.Add(1)
+	}
+
+	switch verdict {
+	case verdictNotice:
+		room.Reply(c, errorMessage(room.ID, notice))
+	case verdictDisconnect:
+		room.throttleDisconnects.Add(1)
+		log.Printf("Cliente %s desconectado de la sala %s por exceder los límites de mensajes", c.UserID, room.ID)
+		return false, false
+	}
+	return false, true
+}
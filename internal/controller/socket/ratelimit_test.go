@@ -0,0 +1,53 @@
+package socket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadLimitCoversEveryType(t *testing.T) {
+	limits := Limits{
+		Connection: MessageLimit{MaxBytes: 1024},
+		Types:      map[string]MessageLimit{MessageOp: {MaxBytes: 4096}, MessageCursor: {MaxBytes: 128}},
+	}
+	if got := limits.readLimit(); got != 4096 {
+		t.Fatalf("se esperaba leer hasta 4096 bytes, se obtuvo %d", got)
+	}
+}
+
+// Un frame que excede el tamaño de su tipo, o el de la conexión si el tipo no tiene uno, se
+// descarta con message_too_large
+func TestCheckRejectsOversizeFrames(t *testing.T) {
+	limits := Limits{
+		Connection: MessageLimit{MaxBytes: 1024},
+		Types: map[string]MessageLimit{
+			MessageOp:     {MaxBytes: 4096},
+			MessageCursor: {MaxBytes: 128},
+			MessageChat:   {Rate: 2, Burst: 5},
+		},
+	}
+	cases := []struct {
+		messageType string
+		size        int
+		allowed     bool
+	}{
+		{MessageOp, 4096, true},
+		{MessageOp, 4097, false},
+		{MessageCursor, 129, false},
+		{MessageChat, 1024, true},
+		{MessageChat, 1025, false},
+		{MessagePing, 2048, false},
+	}
+
+	now := time.Now()
+	for _, tc := range cases {
+		limiter := newRateLimiter(limits)
+		verdict, notice := limiter.check(tc.messageType, tc.size, now)
+		switch {
+		case tc.allowed && verdict != verdictAllow:
+			t.Errorf("%s de %d bytes: se esperaba aceptarlo, se obtuvo %+v", tc.messageType, tc.size, notice)
+		case !tc.allowed && (verdict != verdictNotice || notice.Code != ErrorCodeTooLarge):
+			t.Errorf("%s de %d bytes: se esperaba message_too_large, se obtuvo %v %+v", tc.messageType, tc.size, verdict, notice)
+		}
+	}
+}
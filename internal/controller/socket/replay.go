@@ -0,0 +1,93 @@
+package socket
+
+import (
+	"math/rand/v2"
+)
+
+// replayBufferSize es cuántos frames de la sala se conservan para reanudar sesiones. Debe
+// caber en el buffer de envío del cliente, ya que la repetición no espera a writePump.
+const replayBufferSize = 256
+
+// Cada seq lleva en los bits altos la época de la sala que lo numeró y en los bajos el número
+// del frame. Entre los dos no pasan de 2^53, el mayor entero exacto en JavaScript.
+const (
+	seqCounterBits = 32
+	seqEpochBits   = 21
+)
+
+const MessageResyncRequired = "resync_required" // Servidor -> cliente: no se pudo reanudar desde resume_from
+
+// sequencedFrame es un frame ya enviado a la sala
+type sequencedFrame struct {
+	seq   int64
+	frame *frame
+}
+
+// replayBuffer numera los frames de la sala y guarda los últimos. Solo lo usa la goroutine de la sala.
+type replayBuffer struct {
+	epoch  int64
+	seq    int64
+	frames []sequencedFrame
+}
+
+// newReplayBuffer elige una época al azar para la sala. Cada instancia numera sus frames por
+// su cuenta, así que un seq de otra instancia o de una sala anterior del mismo proyecto tiene
+// otra época y pide resync en vez de mezclarse con estos.
+func newReplayBuffer() *replayBuffer {
+	epoch := rand.Int64N(1<<seqEpochBits-1) + 1
+	return &replayBuffer{epoch: epoch, seq: epoch << seqCounterBits}
+}
+
+func (b *replayBuffer) next() int64 {
+	b.seq++
+	return b.seq
+}
+
+func (b *replayBuffer) record(seq int64, f *frame) {
+	b.frames = append(b.frames, sequencedFrame{seq: seq, frame: f})
+	if len(b.frames) > replayBufferSize {
+		b.frames = b.frames[len(b.frames)-replayBufferSize:]
+	}
+}
+
+// since retorna los frames posteriores a from, o false si alguno ya no está en el buffer
+func (b *replayBuffer) since(from int64) ([]sequencedFrame, bool) {
+	if from>>seqCounterBits != b.epoch || from > b.seq {
+		return nil, false
+	}
+	oldest := b.seq + 1
+	if len(b.frames) > 0 {
+		oldest = b.frames[0].seq
+	}
+	if from < oldest-1 {
+		return nil, false
+	}
+
+	start := len(b.frames)
+	for start > 0 && b.frames[start-1].seq > from {
+		start--
+	}
+	return b.frames[start:], true
+}
+
+// resume repite al cliente los frames que se perdió desde resumeFrom. Si no es posible le envía
+// resync_required y retorna false, y el cliente recibe el estado completo como en una conexión nueva.
+func (r *Room) resume(client *Client, resumeFrom int64) bool {
+	frames, ok := r.frames.since(resumeFrom)
+	if !ok {
+		r.sendTo(client, Message{
+			Type:      MessageResyncRequired,
+			ProjectID: r.ID,
+			Data: map[string]interface{}{
+				"resume_from": resumeFrom,
+				"seq":         r.frames.seq,
+			},
+		})
+		return false
+	}
+
+	for _, sequenced := range frames {
+		r.sendFrame(client, sequenced.frame, priorityEdit)
+	}
+	return true
+}
@@ -1,20 +1,54 @@
 package socket
 
 import (
+	"os"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/backplane"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/middleware"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes configura las rutas para WebSocket
-func SetupRoutes(router *gin.Engine) {
-	handler := NewHandler()
+// Dependencies agrupa los servicios que necesita el paquete socket
+type Dependencies struct {
+	Events   *event.Bus
+	Users    services.UserService
+	Projects services.ProjectService
+	Chat     services.ChatService
+	// Tickets guarda los tickets de conexión para que cualquier instancia pueda canjearlos
+	Tickets services.TicketService
+	// Moderation aplica los silencios y expulsiones; nil desactiva la moderación
+	Moderation services.ModerationService
+	Capacity   Capacity // Límites globales de las salas; los valores en 0 usan DefaultCapacity
+	Limits     Limits   // Límites de los mensajes entrantes; vacío usa DefaultLimits
+	// IdleGrace es cuánto se mantiene abierta una sala vacía; 0 usa 30 segundos
+	IdleGrace time.Duration
+	// UndoDepth es cuántas operaciones puede deshacer cada usuario; 0 usa 100
+	UndoDepth int
+	// Backplane conecta las salas entre instancias; nil si la API corre en una sola
+	Backplane backplane.Backplane
+}
+
+// SetupRoutes configura las rutas para WebSocket. El handler retornado cierra las salas al
+// apagar el servidor.
+func SetupRoutes(router *gin.Engine, deps Dependencies) *Handler {
+	jwt := os.Getenv("JWT_SECRET")
+	handler := NewHandler(deps, NewAuthenticator(jwt, deps.Users, deps.Tickets))
 
 	// Grupo de rutas para WebSocket
 	ws := router.Group("/ws")
 	{
-		// Conexión WebSocket principal
-		// URL: ws://localhost:8080/ws/connect?project_id=123&user_id=456&username=Juan
+		// Conexión WebSocket principal, autenticada con el JWT de la API:
+		//   Authorization: Bearer <jwt>
+		//   Sec-WebSocket-Protocol: access_token, <jwt>
+		//   ws://localhost:8080/ws/connect?project_id=123&ticket=<ticket>
 		ws.GET("/connect", handler.HandleWebSocket())
 
+		// Ticket de un solo uso para clientes que no pueden enviar headers (navegadores)
+		ws.POST("/ticket", middleware.JWTMiddleware(jwt), handler.IssueTicket)
+
 		// Información de una sala específica
 		ws.GET("/room/:project_id", handler.GetRoomInfo)
 
@@ -24,7 +58,13 @@ func SetupRoutes(router *gin.Engine) {
 		// Obtener todas las salas activas
 		ws.GET("/rooms", handler.GetActiveRooms)
 
-		// Expulsar usuario de una sala (para administradores)
+		// Moderación (dueño y administradores del proyecto)
 		ws.DELETE("/room/:project_id/user/:user_id", handler.KickUser)
+		ws.POST("/room/:project_id/user/:user_id/mute", handler.MuteUser)
+		ws.DELETE("/room/:project_id/user/:user_id/mute", handler.UnmuteUser)
+		ws.POST("/room/:project_id/user/:user_id/ban", handler.BanUser)
+		ws.DELETE("/room/:project_id/user/:user_id/ban", handler.UnbanUser)
+		ws.GET("/room/:project_id/moderation", handler.GetModerationLog)
 	}
+	return handler
 }
@@ -0,0 +1,115 @@
+package socket
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// MessageServerShutdown avisa a un cliente que el servidor se detiene y cuándo reconectar
+const MessageServerShutdown = "server_shutdown"
+
+// Espera sugerida para reconectar; cada cliente recibe un valor al azar en el rango para no
+// volver todos a la vez
+const (
+	reconnectAfterMin = time.Second
+	reconnectAfterMax = 5 * time.Second
+)
+
+// Shutdown deja de aceptar conexiones, avisa a los clientes de todas las salas, guarda los
+// documentos y espera a que se envíen los frames de cierre o a que venza ctx
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mutex.Lock()
+	if h.closing {
+		h.mutex.Unlock()
+		return nil
+	}
+	h.closing = true
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mutex.Unlock()
+
+	log.Printf("Cerrando %d salas", len(rooms))
+	for _, room := range rooms {
+		room.stopOnce.Do(func() { close(room.stop) })
+	}
+	for _, room := range rooms {
+		select {
+		case <-room.closed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Los writePump terminan después de escribir el frame de cierre
+	flushed := make(chan struct{})
+	go func() {
+		h.connections.Wait()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acceptConnection registra una conexión nueva, salvo que el hub se esté cerrando
+func (h *Hub) acceptConnection() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closing {
+		return false
+	}
+	h.connections.Add(1)
+	return true
+}
+
+// refuseShutdown cierra la conexión de un cliente que llegó cuando el hub ya se estaba cerrando
+func (h *Hub) refuseShutdown(client *Client) {
+	if frame, err := newFrame(shutdownMessage(client.ProjectID)); err == nil {
+		client.send <- frame
+	}
+	client.closeCode = websocket.CloseServiceRestart
+	client.closeText = "servidor reiniciándose"
+	close(client.send)
+}
+
+// shutdown avisa a los clientes de la sala que el servidor se detiene. run termina después
+// y al salir guarda el documento y cierra los canales, con lo que cada writePump envía lo
+// que tiene pendiente y el frame de cierre.
+func (r *Room) shutdown(hub *Hub) {
+	r.mutex.RLock()
+	clients := make([]*Client, 0, len(r.Clients)+len(r.waiting))
+	for client := range r.Clients {
+		clients = append(clients, client)
+	}
+	clients = append(clients, r.waiting...)
+	r.mutex.RUnlock()
+
+	for _, client := range clients {
+		r.sendTo(client, shutdownMessage(r.ID))
+		client.closeCode = websocket.CloseServiceRestart
+		client.closeText = "servidor reiniciándose"
+	}
+	log.Printf("Sala %s cerrada por apagado del servidor (%d clientes)", r.ID, len(clients))
+	hub.removeRoom(r)
+}
+
+func shutdownMessage(projectID string) Message {
+	jitter := rand.N(reconnectAfterMax - reconnectAfterMin)
+	return Message{
+		Type:      MessageServerShutdown,
+		ProjectID: projectID,
+		Data: map[string]interface{}{
+			"message":            "El servidor se está reiniciando",
+			"reconnect_after_ms": (reconnectAfterMin + jitter).Milliseconds(),
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+type CreateBranchInput struct {
+	Name string `json:"name" binding:"required,min=1,max=64"`
+}
+
+type MergeBranchInput struct {
+	BranchID string `json:"branch_id" binding:"required,uuid"`
+	// Resolutions resuelve los conflictos por nodo: node_id -> "ours" (proyecto) | "theirs" (rama)
+	Resolutions map[string]string `json:"resolutions"`
+}
+
+type MergeBranchResponse struct {
+	Merged    bool               `json:"merged"`
+	Project   *entity.Project    `json:"project,omitempty"`
+	Conflicts []content.Conflict `json:"conflicts"`
+}
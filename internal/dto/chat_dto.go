@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+type ChatHistoryResponse struct {
+	// Messages va del más viejo al más nuevo
+	Messages []entity.ChatMessage `json:"messages"`
+	// NextBefore es el cursor para pedir la página anterior; vacío si no hay más
+	NextBefore string `json:"next_before,omitempty"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "encoding/json"
+
+// ContentTarget direcciona un subárbol del Content por JSON Pointer o por ID de nodo
+type ContentTarget struct {
+	Pointer string
+	NodeID  string
+}
+
+type InsertNodeInput struct {
+	Node json.RawMessage `json:"node" binding:"required"`
+	// Index es la posición entre los hijos; si se omite el nodo se agrega al final
+	Index *int `json:"index" binding:"omitempty,min=0"`
+}
+
+type ContentResponse struct {
+	ProjectID string          `json:"project_id"`
+	Revision  int             `json:"revision"`
+	Pointer   string          `json:"pointer"`
+	Value     json.RawMessage `json:"value,omitempty"`
+}
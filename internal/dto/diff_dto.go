@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+)
+
+type DiffDocumentsInput struct {
+	From json.RawMessage `json:"from" binding:"required"`
+	To   json.RawMessage `json:"to" binding:"required"`
+}
+
+type ProjectDiffResponse struct {
+	ProjectID    string `json:"project_id"`
+	FromRevision int    `json:"from_revision"`
+	ToRevision   int    `json:"to_revision"`
+	*content.Diff
+}
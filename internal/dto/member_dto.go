@@ -0,0 +1,6 @@
+package dto
+
+type AddMemberInput struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+	Role   string `json:"role" binding:"omitempty,oneof=admin editor viewer"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PublishProjectInput struct {
+	Revision int `json:"revision" binding:"omitempty,min=1"`
+}
+
+type PublicProjectResponse struct {
+	Slug        string          `json:"slug"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Revision    int             `json:"revision"`
+	Content     json.RawMessage `json:"content"`
+	PublishedAt *time.Time      `json:"published_at"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+type CreateWebhookInput struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Secret string   `json:"secret" binding:"omitempty,min=16"`
+}
+
+// CreateWebhookResponse es la única respuesta que incluye el secreto de firma
+type CreateWebhookResponse struct {
+	entity.Webhook
+	Secret string `json:"secret"`
+}
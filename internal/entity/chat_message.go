@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ChatMessage struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID uuid.UUID      `gorm:"type:uuid;not null;index:idx_chat_project_created,priority:1" json:"project_id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	Username  string         `gorm:"not null" json:"username"`
+	Text      string         `gorm:"type:text;not null" json:"text"`
+	EditedAt  *time.Time     `json:"edited_at"`
+	CreatedAt time.Time      `gorm:"index:idx_chat_project_created,priority:2" json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
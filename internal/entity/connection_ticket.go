@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConnectionTicket es un ticket de un solo uso para abrir el WebSocket. Se guarda solo el
+// hash del valor, y cualquier instancia de la API puede canjearlo.
+type ConnectionTicket struct {
+	TokenHash string    `gorm:"primaryKey" json:"-"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
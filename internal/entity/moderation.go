@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Acciones de moderación de la sala de un proyecto
+const (
+	ModerationMute   = "mute"
+	ModerationUnmute = "unmute"
+	ModerationKick   = "kick"
+	ModerationBan    = "ban"
+	ModerationUnban  = "unban"
+)
+
+// RoomSanction es un silencio (ModerationMute) o una expulsión temporal (ModerationBan) de
+// la sala de un proyecto. Se levanta borrándola o cuando vence.
+type RoomSanction struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID uuid.UUID      `gorm:"type:uuid;not null;index:idx_sanction_lookup,priority:1" json:"project_id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null;index:idx_sanction_lookup,priority:2" json:"user_id"`
+	Kind      string         `gorm:"not null;index:idx_sanction_lookup,priority:3" json:"kind"`
+	Reason    string         `gorm:"type:text" json:"reason"`
+	ExpiresAt *time.Time     `json:"expires_at"` // nil = hasta que se levante
+	CreatedBy uuid.UUID      `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ModerationLog registra cada acción de moderación para auditoría
+type ModerationLog struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID uuid.UUID  `gorm:"type:uuid;not null;index:idx_moderation_project_created,priority:1" json:"project_id"`
+	ActorID   uuid.UUID  `gorm:"type:uuid;not null" json:"actor_id"`
+	TargetID  uuid.UUID  `gorm:"type:uuid;not null" json:"target_id"`
+	Action    string     `gorm:"not null" json:"action"`
+	Reason    string     `gorm:"type:text" json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `gorm:"index:idx_moderation_project_created,priority:2" json:"created_at"`
+}
@@ -9,14 +9,25 @@ import (
 )
 
 type Project struct {
-	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	Title       string         `gorm:"uniqueIndex" json:"title"`
-	Description string         `json:"description"`
-	Content     datatypes.JSON `gorm:"type:jsonb" json:"content"`
-	OwnerID     uuid.UUID      `json:"owner_id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Title        string         `gorm:"uniqueIndex" json:"title"`
+	Description  string         `json:"description"`
+	Content      datatypes.JSON `gorm:"type:jsonb" json:"content"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	Revision     int            `gorm:"not null;default:1" json:"revision"`
+	ParentID     *uuid.UUID     `gorm:"type:uuid;index" json:"parent_id,omitempty"` // Proyecto del que se bifurcó la rama
+	BranchName   string         `json:"branch_name,omitempty"`
+	BaseRevision int            `json:"base_revision,omitempty"` // Revisión del padre usada como ancestro común
+	// Revisión propia de la rama en su última integración; desde entonces es el ancestro común
+	MergedRevision int `json:"merged_revision,omitempty"`
+
+	// Capacidad de la sala de colaboración; 0 usa el límite global
+	MaxEditors    int `gorm:"not null;default:0" json:"max_editors,omitempty"`
+	MaxSpectators int `gorm:"not null;default:0" json:"max_spectators,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 
 	Owner User `gorm:"foreignKey:OwnerID" json:"owner"`
 }
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles de un usuario dentro de un proyecto
+const (
+	ProjectRoleOwner  = "owner"
+	ProjectRoleAdmin  = "admin"
+	ProjectRoleEditor = "editor"
+	ProjectRoleViewer = "viewer"
+)
+
+type ProjectMember struct {
+	ProjectID uuid.UUID `gorm:"type:uuid;primaryKey" json:"project_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"user_id"`
+	Role      string    `gorm:"not null;default:editor" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// ProjectPublication es la página pública de solo lectura de un proyecto.
+// Guarda su propia copia del Content para no depender de las ediciones en curso.
+type ProjectPublication struct {
+	ID            uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID     uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"project_id"`
+	Slug          string         `gorm:"not null;uniqueIndex" json:"slug"`
+	Revision      int            `gorm:"not null" json:"revision"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	Content       datatypes.JSON `gorm:"type:jsonb" json:"content"`
+	Published     bool           `gorm:"not null;default:false" json:"published"`
+	PublishedAt   *time.Time     `json:"published_at"`
+	UnpublishedAt *time.Time     `json:"unpublished_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// ProjectVersion guarda una copia inmutable del título, la descripción y el Content de un
+// proyecto por cada revisión
+type ProjectVersion struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID   uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_project_revision" json:"project_id"`
+	Revision    int            `gorm:"not null;uniqueIndex:idx_project_revision" json:"revision"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Content     datatypes.JSON `gorm:"type:jsonb" json:"content"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
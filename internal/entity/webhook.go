@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Estados de una entrega de webhook
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryRetrying  = "retrying"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID        uuid.UUID                   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID uuid.UUID                   `gorm:"type:uuid;not null;index" json:"project_id"`
+	URL       string                      `gorm:"not null" json:"url"`
+	Secret    string                      `gorm:"not null" json:"-"`
+	Events    datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"events"`
+	Active    bool                        `gorm:"not null;default:true" json:"active"`
+	CreatedBy uuid.UUID                   `gorm:"type:uuid" json:"created_by"`
+	CreatedAt time.Time                   `json:"created_at"`
+	UpdatedAt time.Time                   `json:"updated_at"`
+	DeletedAt gorm.DeletedAt              `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// Subscribed indica si el webhook escucha el tipo de evento ("*" = todos)
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, e := range w.Events {
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	WebhookID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"webhook_id"`
+	EventID        string         `json:"event_id"`
+	Event          string         `json:"event"`
+	Payload        datatypes.JSON `gorm:"type:jsonb" json:"payload"`
+	Status         string         `gorm:"not null;index" json:"status"`
+	Attempts       int            `json:"attempts"`
+	ResponseStatus int            `json:"response_status"`
+	ResponseBody   string         `json:"response_body"`
+	Error          string         `json:"error"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at"`
+	RedeliveryOf   *uuid.UUID     `gorm:"type:uuid" json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
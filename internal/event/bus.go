@@ -0,0 +1,101 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de eventos de dominio
+const (
+	ProjectCreated   = "project.created"
+	ProjectUpdated   = "project.updated"
+	ProjectDeleted   = "project.deleted"
+	VersionPublished = "version.published"
+	MemberAdded      = "member.added"
+	MemberRemoved    = "member.removed"
+	RoomUserJoined   = "room.user_joined"
+	RoomUserLeft     = "room.user_left"
+	RoomModeration   = "room.moderation"
+)
+
+// Types lista todos los tipos de eventos conocidos
+var Types = []string{
+	ProjectCreated,
+	ProjectUpdated,
+	ProjectDeleted,
+	VersionPublished,
+	MemberAdded,
+	MemberRemoved,
+	RoomUserJoined,
+	RoomUserLeft,
+	RoomModeration,
+}
+
+// Event representa algo que ocurrió en un proyecto
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	ProjectID string      `json:"project_id"`
+	UserID    string      `json:"user_id,omitempty"` // Usuario que originó el evento
+	Data      interface{} `json:"data,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Handler recibe los eventos publicados. Debe retornar rápido, sin bloquear.
+type Handler func(Event)
+
+// Bus distribuye los eventos de dominio dentro del proceso
+type Bus struct {
+	handlers map[int]Handler
+	nextID   int
+	mutex    sync.RWMutex
+}
+
+// NewBus crea un bus sin suscriptores
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[int]Handler),
+	}
+}
+
+// Subscribe registra un handler y retorna la función para cancelar la suscripción
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish completa el ID y la fecha del evento y lo entrega a todos los suscriptores
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	b.mutex.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
@@ -0,0 +1,109 @@
+package event
+
+import "sync"
+
+// Entry es un evento con su número de secuencia dentro del log
+type Entry struct {
+	Seq   uint64
+	Event Event
+}
+
+// Log guarda los últimos eventos del bus en un buffer circular para poder
+// reanudar streams (por ejemplo con Last-Event-ID) y notifica a los suscriptores en vivo.
+type Log struct {
+	entries  []Entry
+	start    int // Posición del evento más antiguo dentro de entries
+	size     int
+	lastSeq  uint64
+	watchers map[chan Entry]struct{}
+	mutex    sync.RWMutex
+}
+
+// watcherBuffer es la cantidad de eventos que un suscriptor lento puede acumular antes de ser desconectado
+const watcherBuffer = 64
+
+// NewLog crea un log con capacidad fija y lo suscribe al bus
+func NewLog(bus *Bus, capacity int) *Log {
+	l := &Log{
+		entries:  make([]Entry, capacity),
+		watchers: make(map[chan Entry]struct{}),
+	}
+	bus.Subscribe(l.append)
+	return l
+}
+
+func (l *Log) append(e Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.lastSeq++
+	entry := Entry{Seq: l.lastSeq, Event: e}
+
+	capacity := len(l.entries)
+	if l.size < capacity {
+		l.entries[(l.start+l.size)%capacity] = entry
+		l.size++
+	} else {
+		l.entries[l.start] = entry
+		l.start = (l.start + 1) % capacity
+	}
+
+	for ch := range l.watchers {
+		select {
+		case ch <- entry:
+		default:
+			// El suscriptor no consume: se cierra su canal y deberá reanudar desde su último Seq
+			delete(l.watchers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Since retorna los eventos posteriores a seq. complete es false si parte de ellos
+// ya salió del buffer y el consumidor debe resincronizar su estado.
+func (l *Log) Since(seq uint64) (entries []Entry, complete bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if l.size == 0 || seq >= l.lastSeq {
+		return nil, seq <= l.lastSeq
+	}
+
+	oldest := l.entries[l.start].Seq
+	complete = seq+1 >= oldest
+
+	capacity := len(l.entries)
+	for i := 0; i < l.size; i++ {
+		entry := l.entries[(l.start+i)%capacity]
+		if entry.Seq > seq {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, complete
+}
+
+// LastSeq retorna el número de secuencia del último evento registrado
+func (l *Log) LastSeq() uint64 {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.lastSeq
+}
+
+// Watch entrega los eventos nuevos por un canal hasta que se llame a la función de cancelación.
+// El canal se cierra si el consumidor se queda atrás.
+func (l *Log) Watch() (<-chan Entry, func()) {
+	ch := make(chan Entry, watcherBuffer)
+
+	l.mutex.Lock()
+	l.watchers[ch] = struct{}{}
+	l.mutex.Unlock()
+
+	return ch, func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if _, ok := l.watchers[ch]; ok {
+			delete(l.watchers, ch)
+			close(ch)
+		}
+	}
+}
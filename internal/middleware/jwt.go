@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/google/uuid"
 )
 
+var ErrInvalidToken = errors.New("invalid token")
+
 func JWTMiddleware(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -24,11 +27,8 @@ func JWTMiddleware(jwtSecret string) gin.HandlerFunc {
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
 		// Verificar token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		uid, claims, err := ParseToken(tokenString, jwtSecret)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Token inválido",
 			})
@@ -36,17 +36,31 @@ func JWTMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// Extraer claims y guardar en context
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if uidRaw, ok := claims["user_id"].(string); ok {
-				uid, err := uuid.Parse(uidRaw)
-				if err == nil {
-					c.Set("user_id", uid)
-				}
-			}
-			c.Set("email", claims["email"])
-		}
+		// Guardar claims en context
+		c.Set("user_id", uid)
+		c.Set("email", claims["email"])
 
 		c.Next()
 	}
 }
+
+// ParseToken verifica la firma y expiración del token y retorna el usuario de sus claims
+func ParseToken(tokenString, jwtSecret string) (uuid.UUID, jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return uuid.Nil, nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil, nil, ErrInvalidToken
+	}
+	uidRaw, _ := claims["user_id"].(string)
+	uid, err := uuid.Parse(uidRaw)
+	if err != nil {
+		return uuid.Nil, nil, ErrInvalidToken
+	}
+	return uid, claims, nil
+}
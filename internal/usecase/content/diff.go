@@ -0,0 +1,525 @@
+package content
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Tipos de cambios semánticos
+const (
+	ChangeNodeAdded       = "node_added"
+	ChangeNodeRemoved     = "node_removed"
+	ChangeNodeMoved       = "node_moved"
+	ChangePropertyChanged = "property_changed"
+)
+
+// PatchOperation es una operación de JSON Patch (RFC 6902)
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON incluye value en add, replace y test aunque el valor sea null: el RFC lo exige
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	type plain PatchOperation
+	if op.Op != "add" && op.Op != "replace" && op.Op != "test" {
+		return json.Marshal(plain(op))
+	}
+	return json.Marshal(struct {
+		plain
+		Value interface{} `json:"value"`
+	}{plain(op), op.Value})
+}
+
+// Change es un cambio sobre un nodo del árbol, identificado por su ID
+type Change struct {
+	Op           string      `json:"op"`
+	NodeID       string      `json:"node_id"`
+	NodeType     string      `json:"node_type,omitempty"`
+	ParentID     string      `json:"parent_id,omitempty"`
+	Index        *int        `json:"index,omitempty"`
+	FromParentID string      `json:"from_parent_id,omitempty"`
+	FromIndex    *int        `json:"from_index,omitempty"`
+	Descendants  int         `json:"descendants,omitempty"`
+	Property     string      `json:"property,omitempty"`
+	OldValue     interface{} `json:"old_value,omitempty"`
+	NewValue     interface{} `json:"new_value,omitempty"`
+}
+
+// Diff es la diferencia entre dos documentos en forma de patch y de cambios por nodo
+type Diff struct {
+	Patch   []PatchOperation `json:"patch"`
+	Changes []Change         `json:"changes"`
+	Summary []string         `json:"summary"`
+}
+
+// DiffDocuments compara dos Content ya serializados
+func DiffDocuments(from, to []byte) (*Diff, error) {
+	a, err := Parse(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Parse(to)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(a, b), nil
+}
+
+// Compare calcula el patch RFC 6902 que transforma a en b y los cambios semánticos del árbol
+func Compare(a, b map[string]interface{}) *Diff {
+	d := &Diff{
+		Patch:   []PatchOperation{},
+		Changes: []Change{},
+		Summary: []string{},
+	}
+	diffValue("", a, b, &d.Patch)
+
+	before, after := newTreeIndex(a), newTreeIndex(b)
+	d.Changes = treeChanges(before, after)
+	for _, change := range d.Changes {
+		d.Summary = append(d.Summary, describeChange(change, before, after))
+	}
+	return d
+}
+
+/* ---------- JSON Patch ---------- */
+
+func diffValue(path string, a, b interface{}, ops *[]PatchOperation) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			diffObject(path, av, bv, ops)
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			if keyedArray(av) && keyedArray(bv) {
+				diffKeyedArray(path, av, bv, ops)
+			} else {
+				diffArray(path, av, bv, ops)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*ops = append(*ops, PatchOperation{Op: "replace", Path: path, Value: b})
+	}
+}
+
+func diffObject(path string, a, b map[string]interface{}, ops *[]PatchOperation) {
+	for _, key := range sortedKeys(a) {
+		if _, ok := b[key]; !ok {
+			*ops = append(*ops, PatchOperation{Op: "remove", Path: path + "/" + EscapePointerToken(key)})
+		}
+	}
+	for _, key := range sortedKeys(b) {
+		child := path + "/" + EscapePointerToken(key)
+		if old, ok := a[key]; ok {
+			diffValue(child, old, b[key], ops)
+		} else {
+			*ops = append(*ops, PatchOperation{Op: "add", Path: child, Value: b[key]})
+		}
+	}
+}
+
+// diffArray compara arreglos sin IDs posición por posición
+func diffArray(path string, a, b []interface{}, ops *[]PatchOperation) {
+	common := len(a)
+	if len(b) < common {
+		common = len(b)
+	}
+	for i := 0; i < common; i++ {
+		diffValue(path+"/"+strconv.Itoa(i), a[i], b[i], ops)
+	}
+	for i := len(a) - 1; i >= len(b); i-- {
+		*ops = append(*ops, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+	}
+	for i := len(a); i < len(b); i++ {
+		*ops = append(*ops, PatchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: b[i]})
+	}
+}
+
+// diffKeyedArray compara arreglos de nodos usando sus IDs, generando remove/add/move.
+// Las operaciones se emiten en el orden en que deben aplicarse.
+func diffKeyedArray(path string, a, b []interface{}, ops *[]PatchOperation) {
+	oldNodes := make(map[string]interface{}, len(a))
+	current := make([]string, 0, len(a))
+	for _, value := range a {
+		id := value.(map[string]interface{})[IDKey].(string)
+		oldNodes[id] = value
+		current = append(current, id)
+	}
+
+	keep := make(map[string]bool, len(b))
+	for _, value := range b {
+		keep[value.(map[string]interface{})[IDKey].(string)] = true
+	}
+
+	for i := len(current) - 1; i >= 0; i-- {
+		if !keep[current[i]] {
+			*ops = append(*ops, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+			current = append(current[:i], current[i+1:]...)
+		}
+	}
+
+	for i, value := range b {
+		id := value.(map[string]interface{})[IDKey].(string)
+		target := path + "/" + strconv.Itoa(i)
+
+		old, existed := oldNodes[id]
+		if !existed {
+			*ops = append(*ops, PatchOperation{Op: "add", Path: target, Value: value})
+			current = insertAt(current, i, id)
+			continue
+		}
+
+		// Las posiciones anteriores a i ya coinciden con b, así que el nodo está en j >= i
+		if j := indexOf(current, id); j != i {
+			*ops = append(*ops, PatchOperation{Op: "move", From: path + "/" + strconv.Itoa(j), Path: target})
+			current = append(current[:j], current[j+1:]...)
+			current = insertAt(current, i, id)
+		}
+		diffValue(target, old, value, ops)
+	}
+}
+
+// keyedArray indica si todos los elementos son objetos con un "id" único
+func keyedArray(values []interface{}) bool {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		node, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		id, ok := node[IDKey].(string)
+		if !ok || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+/* ---------- Cambios semánticos ---------- */
+
+func treeChanges(before, after *treeIndex) []Change {
+	var changes []Change
+
+	// Nodos eliminados: solo la raíz de cada subárbol eliminado
+	for _, id := range sortedNodeIDs(before) {
+		info := before.nodes[id]
+		if _, ok := after.nodes[id]; ok || info.Index < 0 {
+			continue
+		}
+		if _, parentKept := after.nodes[info.ParentID]; !parentKept {
+			continue
+		}
+		changes = append(changes, Change{
+			Op:          ChangeNodeRemoved,
+			NodeID:      id,
+			NodeType:    info.Type,
+			ParentID:    info.ParentID,
+			Index:       intPtr(info.Index),
+			Descendants: before.countDescendants(id),
+		})
+	}
+
+	// Nodos agregados: solo la raíz de cada subárbol nuevo
+	for _, id := range sortedNodeIDs(after) {
+		info := after.nodes[id]
+		if _, ok := before.nodes[id]; ok || info.Index < 0 {
+			continue
+		}
+		if _, parentExisted := before.nodes[info.ParentID]; !parentExisted {
+			continue
+		}
+		changes = append(changes, Change{
+			Op:          ChangeNodeAdded,
+			NodeID:      id,
+			NodeType:    info.Type,
+			ParentID:    info.ParentID,
+			Index:       intPtr(info.Index),
+			Descendants: after.countDescendants(id),
+		})
+	}
+
+	moved := movedNodes(before, after)
+	for _, id := range sortedNodeIDs(after) {
+		old, ok := before.nodes[id]
+		if !ok {
+			continue
+		}
+		info := after.nodes[id]
+
+		if moved[id] {
+			changes = append(changes, Change{
+				Op:           ChangeNodeMoved,
+				NodeID:       id,
+				NodeType:     info.Type,
+				ParentID:     info.ParentID,
+				Index:        intPtr(info.Index),
+				FromParentID: old.ParentID,
+				FromIndex:    intPtr(old.Index),
+			})
+		}
+
+		keys := make(map[string]bool)
+		for key := range old.Node {
+			keys[key] = true
+		}
+		for key := range info.Node {
+			keys[key] = true
+		}
+		for _, key := range sortedSet(keys) {
+			if !isProperty(key) {
+				continue
+			}
+			oldValue, newValue := old.Node[key], info.Node[key]
+			if reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			changes = append(changes, Change{
+				Op:       ChangePropertyChanged,
+				NodeID:   id,
+				NodeType: info.Type,
+				Property: key,
+				OldValue: oldValue,
+				NewValue: newValue,
+			})
+		}
+	}
+
+	return changes
+}
+
+// movedNodes detecta los nodos que cambiaron de padre o cuyo orden relativo entre
+// sus hermanos cambió. Los desplazamientos causados por inserciones o eliminaciones
+// de otros nodos no cuentan como movimientos.
+func movedNodes(before, after *treeIndex) map[string]bool {
+	moved := make(map[string]bool)
+
+	for id, info := range after.nodes {
+		if old, ok := before.nodes[id]; ok && old.ParentID != info.ParentID && info.Index >= 0 {
+			moved[id] = true
+		}
+	}
+
+	for parentID, newChildren := range after.children {
+		oldChildren, ok := before.children[parentID]
+		if !ok {
+			continue
+		}
+
+		inOld := make(map[string]bool, len(oldChildren))
+		for _, id := range oldChildren {
+			inOld[id] = true
+		}
+		inNew := make(map[string]bool, len(newChildren))
+		for _, id := range newChildren {
+			inNew[id] = true
+		}
+
+		var oldSeq, newSeq []string
+		for _, id := range oldChildren {
+			if inNew[id] {
+				oldSeq = append(oldSeq, id)
+			}
+		}
+		for _, id := range newChildren {
+			if inOld[id] {
+				newSeq = append(newSeq, id)
+			}
+		}
+
+		stable := longestCommonSubsequence(oldSeq, newSeq)
+		for _, id := range newSeq {
+			if !stable[id] {
+				moved[id] = true
+			}
+		}
+	}
+	return moved
+}
+
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	result := make(map[string]bool)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			result[a[i]] = true
+			i++
+			j++
+		case table[i][j+1] >= table[i+1][j]:
+			j++
+		default:
+			i++
+		}
+	}
+	return result
+}
+
+/* ---------- Resumen legible ---------- */
+
+func describeChange(change Change, before, after *treeIndex) string {
+	node := describeNode(change.NodeType, change.NodeID)
+
+	switch change.Op {
+	case ChangeNodeAdded:
+		text := fmt.Sprintf("Added %s to %s at position %d", node, describeParent(after, change.ParentID), *change.Index+1)
+		return text + describeNested(change.Descendants)
+	case ChangeNodeRemoved:
+		text := fmt.Sprintf("Removed %s from %s", node, describeParent(before, change.ParentID))
+		return text + describeNested(change.Descendants)
+	case ChangeNodeMoved:
+		if change.FromParentID == change.ParentID {
+			return fmt.Sprintf("Moved %s within %s from position %d to %d",
+				node, describeParent(after, change.ParentID), *change.FromIndex+1, *change.Index+1)
+		}
+		return fmt.Sprintf("Moved %s from %s to %s",
+			node, describeParent(before, change.FromParentID), describeParent(after, change.ParentID))
+	case ChangePropertyChanged:
+		switch {
+		case change.OldValue == nil:
+			return fmt.Sprintf("Set %q of %s to %s", change.Property, node, shortValue(change.NewValue))
+		case change.NewValue == nil:
+			return fmt.Sprintf("Removed %q from %s", change.Property, node)
+		default:
+			return fmt.Sprintf("Changed %q of %s from %s to %s",
+				change.Property, node, shortValue(change.OldValue), shortValue(change.NewValue))
+		}
+	}
+	return change.Op + " " + node
+}
+
+func describeNode(nodeType, id string) string {
+	if id == RootID {
+		return "the root"
+	}
+	if nodeType == "" {
+		return fmt.Sprintf("node %q", id)
+	}
+	return fmt.Sprintf("%s %q", nodeType, id)
+}
+
+func describeParent(idx *treeIndex, id string) string {
+	if info, ok := idx.nodes[id]; ok {
+		return describeNode(info.Type, id)
+	}
+	return describeNode("", id)
+}
+
+func describeNested(count int) string {
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return " (with 1 nested node)"
+	default:
+		return fmt.Sprintf(" (with %d nested nodes)", count)
+	}
+}
+
+func shortValue(value interface{}) string {
+	const maxLength = 60
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(raw) > maxLength {
+		return string(raw[:maxLength-3]) + "..."
+	}
+	return string(raw)
+}
+
+/* ---------- Helpers ---------- */
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedSet(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedNodeIDs retorna los IDs en el orden del documento (por JSON Pointer)
+func sortedNodeIDs(idx *treeIndex) []string {
+	ids := make([]string, 0, len(idx.nodes))
+	for id := range idx.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return pointerLess(idx.nodes[ids[i]].Path, idx.nodes[ids[j]].Path)
+	})
+	return ids
+}
+
+// pointerLess ordena punteros comparando los índices numéricamente
+func pointerLess(a, b string) bool {
+	ta, _ := ParsePointer(a)
+	tb, _ := ParsePointer(b)
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		if ta[i] == tb[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(ta[i])
+		nb, errB := strconv.Atoi(tb[i])
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+		return ta[i] < tb[i]
+	}
+	return len(ta) < len(tb)
+}
+
+func indexOf(values []string, target string) int {
+	for i, value := range values {
+		if value == target {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertAt(values []string, index int, value string) []string {
+	values = append(values, "")
+	copy(values[index+1:], values[index:])
+	values[index] = value
+	return values
+}
+
+func intPtr(value int) *int {
+	return &value
+}
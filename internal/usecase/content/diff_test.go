@@ -0,0 +1,108 @@
+package content
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func mustParse(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	doc, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func patchJSON(t *testing.T, d *Diff) string {
+	t.Helper()
+	raw, err := json.Marshal(d.Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+// Un add o replace con valor null conserva "value"; remove y move no lo llevan
+func TestComparePatchKeepsNullValues(t *testing.T) {
+	a := mustParse(t, `{"id":"root","children":[{"id":"a","type":"Text","text":"hola","color":"red"}]}`)
+	b := mustParse(t, `{"id":"root","children":[{"id":"a","type":"Text","text":null,"icon":null}]}`)
+
+	want := `[{"op":"remove","path":"/children/0/color"},` +
+		`{"op":"add","path":"/children/0/icon","value":null},` +
+		`{"op":"replace","path":"/children/0/text","value":null}]`
+	if got := patchJSON(t, Compare(a, b)); got != want {
+		t.Fatalf("patch inesperado:\nse obtuvo %s\nse esperaba %s", got, want)
+	}
+}
+
+// Reordenar hermanos genera un move y solo cuenta como movido el nodo que cambió de orden
+func TestCompareMovedNodes(t *testing.T) {
+	a := mustParse(t, `{"id":"root","children":[{"id":"a"},{"id":"b"},{"id":"c","children":[]}]}`)
+	b := mustParse(t, `{"id":"root","children":[{"id":"c","children":[{"id":"b"}]},{"id":"a"}]}`)
+
+	d := Compare(a, b)
+	want := `[{"op":"remove","path":"/children/1"},` +
+		`{"op":"move","path":"/children/0","from":"/children/1"},` +
+		`{"op":"add","path":"/children/0/children/0","value":{"id":"b"}}]`
+	if got := patchJSON(t, d); got != want {
+		t.Fatalf("patch inesperado:\nse obtuvo %s\nse esperaba %s", got, want)
+	}
+
+	changes := []Change{
+		{Op: ChangeNodeMoved, NodeID: "c", ParentID: "root", Index: intPtr(0), FromParentID: "root", FromIndex: intPtr(2)},
+		{Op: ChangeNodeMoved, NodeID: "b", ParentID: "c", Index: intPtr(0), FromParentID: "root", FromIndex: intPtr(1)},
+	}
+	if !reflect.DeepEqual(d.Changes, changes) {
+		t.Fatalf("cambios inesperados:\nse obtuvo %+v\nse esperaba %+v", d.Changes, changes)
+	}
+	summary := []string{
+		`Moved node "c" within node "root" from position 3 to 1`,
+		`Moved node "b" from node "root" to node "c"`,
+	}
+	if !reflect.DeepEqual(d.Summary, summary) {
+		t.Fatalf("resumen inesperado: %q", d.Summary)
+	}
+}
+
+// Un cambio dentro de una propiedad anidada es un replace puntual en el patch y un
+// property_changed de la propiedad completa
+func TestCompareNestedPropertyChange(t *testing.T) {
+	a := mustParse(t, `{"id":"root","children":[{"id":"a","type":"Container","style":{"color":"red","padding":[8,8]}}]}`)
+	b := mustParse(t, `{"id":"root","children":[{"id":"a","type":"Container","style":{"color":"blue","padding":[8,8,4]}}]}`)
+
+	d := Compare(a, b)
+	want := `[{"op":"replace","path":"/children/0/style/color","value":"blue"},` +
+		`{"op":"add","path":"/children/0/style/padding/2","value":4}]`
+	if got := patchJSON(t, d); got != want {
+		t.Fatalf("patch inesperado:\nse obtuvo %s\nse esperaba %s", got, want)
+	}
+
+	changes := []Change{{
+		Op:       ChangePropertyChanged,
+		NodeID:   "a",
+		NodeType: "Container",
+		Property: "style",
+		OldValue: map[string]interface{}{"color": "red", "padding": []interface{}{float64(8), float64(8)}},
+		NewValue: map[string]interface{}{"color": "blue", "padding": []interface{}{float64(8), float64(8), float64(4)}},
+	}}
+	if !reflect.DeepEqual(d.Changes, changes) {
+		t.Fatalf("cambios inesperados:\nse obtuvo %+v\nse esperaba %+v", d.Changes, changes)
+	}
+}
+
+// Agregar o eliminar un subárbol es un solo cambio con la cantidad de nodos anidados
+func TestCompareSubtreeChanges(t *testing.T) {
+	a := mustParse(t, `{"id":"root","children":[{"id":"old","type":"Column","children":[{"id":"x"},{"id":"y"}]}]}`)
+	b := mustParse(t, `{"id":"root","children":[{"id":"new","type":"Row","children":[{"id":"z"}]}]}`)
+
+	changes := Compare(a, b).Changes
+	want := []Change{
+		{Op: ChangeNodeRemoved, NodeID: "old", NodeType: "Column", ParentID: "root", Index: intPtr(0), Descendants: 2},
+		{Op: ChangeNodeAdded, NodeID: "new", NodeType: "Row", ParentID: "root", Index: intPtr(0), Descendants: 1},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("cambios inesperados:\nse obtuvo %+v\nse esperaba %+v", changes, want)
+	}
+}
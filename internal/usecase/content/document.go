@@ -0,0 +1,239 @@
+// Package content trabaja sobre el árbol de widgets guardado en Project.Content.
+//
+// Un nodo es cualquier objeto JSON con un "id" de tipo string. Sus hijos están en el
+// arreglo "children" y el resto de las claves son propiedades. La raíz es el propio
+// documento; si no tiene "id" se la identifica con RootID.
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	IDKey       = "id"
+	TypeKey     = "type"
+	ChildrenKey = "children"
+
+	// RootID identifica a la raíz del documento cuando no tiene "id" propio
+	RootID = ""
+)
+
+var ErrInvalidDocument = errors.New("content must be a JSON object")
+
+// Parse decodifica el Content de un proyecto. Un documento vacío es un objeto vacío.
+func Parse(raw []byte) (map[string]interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return map[string]interface{}{}, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidDocument
+	}
+	return doc, nil
+}
+
+// nodeInfo describe la ubicación de un nodo dentro del árbol
+type nodeInfo struct {
+	ID       string
+	Type     string
+	ParentID string
+	Index    int
+	Path     string // JSON Pointer hasta el nodo
+	Node     map[string]interface{}
+}
+
+// treeIndex indexa los nodos de un documento por ID
+type treeIndex struct {
+	nodes    map[string]*nodeInfo
+	children map[string][]string // IDs de los hijos de cada nodo, en orden
+}
+
+func newTreeIndex(doc map[string]interface{}) *treeIndex {
+	idx := &treeIndex{
+		nodes:    make(map[string]*nodeInfo),
+		children: make(map[string][]string),
+	}
+	root := &nodeInfo{ID: nodeID(doc), Type: nodeType(doc), Index: -1, Path: "", Node: doc}
+	idx.nodes[root.ID] = root
+	idx.walk(root)
+	return idx
+}
+
+func (idx *treeIndex) walk(parent *nodeInfo) {
+	children, _ := parent.Node[ChildrenKey].([]interface{})
+	for i, value := range children {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := child[IDKey].(string)
+		if !ok {
+			continue
+		}
+		if _, dup := idx.nodes[id]; dup {
+			// Los IDs duplicados no se pueden direccionar; se conserva el primero
+			continue
+		}
+
+		info := &nodeInfo{
+			ID:       id,
+			Type:     nodeType(child),
+			ParentID: parent.ID,
+			Index:    i,
+			Path:     parent.Path + "/" + ChildrenKey + "/" + strconv.Itoa(i),
+			Node:     child,
+		}
+		idx.nodes[id] = info
+		idx.children[parent.ID] = append(idx.children[parent.ID], id)
+		idx.walk(info)
+	}
+}
+
+// isDescendant indica si id está dentro del subárbol de ancestorID
+func (idx *treeIndex) isDescendant(id, ancestorID string) bool {
+	for {
+		info, ok := idx.nodes[id]
+		if !ok || info.Index < 0 {
+			return false
+		}
+		if info.ParentID == ancestorID {
+			return true
+		}
+		id = info.ParentID
+	}
+}
+
+// countDescendants cuenta los nodos que hay debajo de id
+func (idx *treeIndex) countDescendants(id string) int {
+	total := 0
+	for _, child := range idx.children[id] {
+		total += 1 + idx.countDescendants(child)
+	}
+	return total
+}
+
+func nodeID(node map[string]interface{}) string {
+	id, _ := node[IDKey].(string)
+	return id
+}
+
+func nodeType(node map[string]interface{}) string {
+	t, _ := node[TypeKey].(string)
+	return t
+}
+
+// IsNodePath indica si la ruta apunta a un nodo: la raíz o una secuencia de children/<índice>
+func IsNodePath(path []string) bool {
+	if len(path)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(path); i += 2 {
+		if path[i] != ChildrenKey {
+			return false
+		}
+		if _, err := strconv.Atoi(path[i+1]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SubtreeIDs lista el id del nodo, si lo tiene, y los de todos sus descendientes. Retorna
+// false si un hijo no es un objeto con id de tipo string o si un id se repite.
+func SubtreeIDs(node map[string]interface{}) ([]string, bool) {
+	var ids []string
+	seen := make(map[string]bool)
+	var walk func(node map[string]interface{}) bool
+	walk = func(node map[string]interface{}) bool {
+		raw, ok := node[ChildrenKey]
+		if !ok {
+			return true
+		}
+		children, ok := raw.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, value := range children {
+			child, ok := value.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			id, ok := child[IDKey].(string)
+			if !ok || id == "" || seen[id] {
+				return false
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			if !walk(child) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if id, ok := node[IDKey].(string); ok {
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if !walk(node) {
+		return nil, false
+	}
+	return ids, true
+}
+
+// isProperty indica si la clave es una propiedad del nodo y no parte de la estructura
+func isProperty(key string) bool {
+	return key != IDKey && key != ChildrenKey
+}
+
+// EscapePointerToken escapa un segmento de JSON Pointer (RFC 6901)
+func EscapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// ParsePointer separa un JSON Pointer en sus segmentos ya desescapados
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("JSON pointer must start with '/'")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// NodePointer retorna los segmentos del JSON Pointer del nodo con ese ID
+func NodePointer(doc map[string]interface{}, id string) ([]string, bool) {
+	info, ok := newTreeIndex(doc).nodes[id]
+	if !ok {
+		return nil, false
+	}
+	tokens, err := ParsePointer(info.Path)
+	if err != nil {
+		return nil, false
+	}
+	return tokens, true
+}
+
+// FormatPointer construye un JSON Pointer a partir de sus segmentos
+func FormatPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(EscapePointerToken(token))
+	}
+	return b.String()
+}
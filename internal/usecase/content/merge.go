@@ -0,0 +1,472 @@
+package content
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Resoluciones posibles de un conflicto
+const (
+	ResolveOurs   = "ours"
+	ResolveTheirs = "theirs"
+)
+
+// Tipos de conflicto de un merge
+const (
+	ConflictProperty     = "property"
+	ConflictDeleteModify = "delete_modify"
+	ConflictAddAdd       = "add_add"
+	ConflictMove         = "move"
+	ConflictCycle        = "cycle"
+)
+
+// Conflict describe un nodo que ambas ramas cambiaron de forma incompatible
+type Conflict struct {
+	NodeID     string                 `json:"node_id"`
+	NodeType   string                 `json:"node_type,omitempty"`
+	Kind       string                 `json:"kind"`
+	Properties []string               `json:"properties,omitempty"`
+	Base       map[string]interface{} `json:"base"`
+	Ours       map[string]interface{} `json:"ours"`
+	Theirs     map[string]interface{} `json:"theirs"`
+}
+
+// MergeResult es el documento combinado o la lista de conflictos pendientes
+type MergeResult struct {
+	Document  map[string]interface{} `json:"-"`
+	Conflicts []Conflict             `json:"conflicts"`
+}
+
+// mergedNode es el estado combinado de un nodo antes de reconstruir el árbol
+type mergedNode struct {
+	props    map[string]interface{}
+	parentID string
+}
+
+type merger struct {
+	base, ours, theirs *treeIndex
+	resolutions        map[string]string
+	nodes              map[string]*mergedNode
+	conflicts          map[string]*Conflict
+}
+
+// Merge combina ours y theirs a partir de su ancestro común base. Los conflictos se
+// resuelven por nodo con resolutions (node_id -> "ours" | "theirs"); los que queden
+// sin resolver se retornan y Document es nil.
+func Merge(base, ours, theirs map[string]interface{}, resolutions map[string]string) *MergeResult {
+	m := &merger{
+		base:        newTreeIndex(base),
+		ours:        newTreeIndex(ours),
+		theirs:      newTreeIndex(theirs),
+		resolutions: resolutions,
+		nodes:       make(map[string]*mergedNode),
+		conflicts:   make(map[string]*Conflict),
+	}
+
+	rootID := nodeID(ours)
+	for _, id := range m.allIDs() {
+		m.mergeNode(id, id == rootID)
+	}
+	m.dropOrphans(rootID)
+	m.breakCycles(rootID)
+
+	result := &MergeResult{Conflicts: []Conflict{}}
+	for _, id := range sortedSet(keysOf(m.conflicts)) {
+		result.Conflicts = append(result.Conflicts, *m.conflicts[id])
+	}
+	if len(result.Conflicts) == 0 {
+		result.Document = m.build(rootID)
+	}
+	return result
+}
+
+func (m *merger) allIDs() []string {
+	ids := make(map[string]bool)
+	for _, idx := range []*treeIndex{m.base, m.ours, m.theirs} {
+		for id := range idx.nodes {
+			ids[id] = true
+		}
+	}
+	return sortedSet(ids)
+}
+
+func (m *merger) mergeNode(id string, isRoot bool) {
+	b, inBase := m.base.nodes[id]
+	o, inOurs := m.ours.nodes[id]
+	t, inTheirs := m.theirs.nodes[id]
+
+	if isRoot {
+		// La raíz siempre existe; solo se combinan sus propiedades
+		m.mergeExisting(id, rootInfo(m.base, b), o, rootInfo(m.theirs, t))
+		return
+	}
+	for _, info := range []*nodeInfo{b, o, t} {
+		if info != nil && info.Index < 0 {
+			// Raíz de otra versión con un ID distinto
+			return
+		}
+	}
+
+	switch {
+	case inBase && inOurs && inTheirs:
+		m.mergeExisting(id, b, o, t)
+
+	case inBase && !inOurs && !inTheirs:
+		// Eliminado en ambas ramas
+
+	case inBase && !inOurs:
+		m.mergeDeleted(id, b, t, ResolveOurs)
+
+	case inBase && !inTheirs:
+		m.mergeDeleted(id, b, o, ResolveTheirs)
+
+	case inOurs && inTheirs:
+		// Agregado en ambas ramas con el mismo ID
+		if o.ParentID == t.ParentID && reflect.DeepEqual(properties(o.Node), properties(t.Node)) {
+			m.keep(id, o)
+			return
+		}
+		switch m.resolutions[id] {
+		case ResolveOurs:
+			m.keep(id, o)
+		case ResolveTheirs:
+			m.keep(id, t)
+		default:
+			m.conflict(id, ConflictAddAdd, nil, nil, o, t)
+		}
+
+	case inOurs:
+		m.keep(id, o)
+
+	case inTheirs:
+		m.keep(id, t)
+	}
+}
+
+// mergeExisting combina un nodo presente en las tres versiones
+func (m *merger) mergeExisting(id string, b, o, t *nodeInfo) {
+	if b == nil {
+		b = o
+	}
+	if t == nil {
+		t = o
+	}
+	baseProps, ourProps, theirProps := properties(b.Node), properties(o.Node), properties(t.Node)
+
+	props := make(map[string]interface{})
+	var conflicting []string
+	keys := make(map[string]bool)
+	for _, p := range []map[string]interface{}{baseProps, ourProps, theirProps} {
+		for key := range p {
+			keys[key] = true
+		}
+	}
+	for _, key := range sortedSet(keys) {
+		value, ok := merge3(baseProps[key], ourProps[key], theirProps[key])
+		if !ok {
+			conflicting = append(conflicting, key)
+			value = m.pick(id, ourProps[key], theirProps[key])
+		}
+		if value != nil {
+			props[key] = value
+		}
+	}
+
+	parentID, ok := merge3String(b.ParentID, o.ParentID, t.ParentID)
+	moved := !ok
+	if moved {
+		parentID = o.ParentID
+		if m.resolutions[id] == ResolveTheirs {
+			parentID = t.ParentID
+		}
+	}
+
+	m.nodes[id] = &mergedNode{props: props, parentID: parentID}
+
+	if m.resolutions[id] != ResolveOurs && m.resolutions[id] != ResolveTheirs {
+		switch {
+		case len(conflicting) > 0:
+			m.conflict(id, ConflictProperty, conflicting, b, o, t)
+		case moved:
+			m.conflict(id, ConflictMove, nil, b, o, t)
+		}
+	}
+}
+
+// mergeDeleted combina un nodo que una rama eliminó y la otra (changed) conservó.
+// deletedBy indica qué resolución equivale a aceptar la eliminación.
+func (m *merger) mergeDeleted(id string, b, changed *nodeInfo, deletedBy string) {
+	switch m.resolutions[id] {
+	case deletedBy:
+		return
+	case ResolveOurs, ResolveTheirs:
+		m.keep(id, changed)
+		return
+	}
+
+	modified := changed.ParentID != b.ParentID || !reflect.DeepEqual(properties(b.Node), properties(changed.Node))
+	if modified {
+		if deletedBy == ResolveOurs {
+			m.conflict(id, ConflictDeleteModify, nil, b, nil, changed)
+		} else {
+			m.conflict(id, ConflictDeleteModify, nil, b, changed, nil)
+		}
+	}
+}
+
+// dropOrphans elimina los nodos cuyo padre no sobrevivió. Si el padre fue eliminado
+// sin resolución explícita es un conflicto: la otra rama le agregó o movió hijos.
+func (m *merger) dropOrphans(rootID string) {
+	for changed := true; changed; {
+		changed = false
+		for _, id := range sortedSet(keysOf(m.nodes)) {
+			node := m.nodes[id]
+			if id == rootID {
+				continue
+			}
+			if _, ok := m.nodes[node.parentID]; ok {
+				continue
+			}
+
+			parentID := node.parentID
+			if _, resolved := m.resolutions[parentID]; !resolved && m.conflicts[parentID] == nil {
+				if b, ok := m.base.nodes[parentID]; ok {
+					m.conflict(parentID, ConflictDeleteModify, nil, b, m.ours.nodes[parentID], m.theirs.nodes[parentID])
+				}
+			}
+			delete(m.nodes, id)
+			changed = true
+		}
+	}
+}
+
+// breakCycles detecta movimientos cruzados (A dentro de B en una rama y B dentro de A en la otra)
+func (m *merger) breakCycles(rootID string) {
+	for _, id := range sortedSet(keysOf(m.nodes)) {
+		seen := map[string]bool{id: true}
+		for current := m.nodes[id].parentID; current != rootID; {
+			node, ok := m.nodes[current]
+			if !ok {
+				break
+			}
+			if seen[current] {
+				m.conflict(id, ConflictCycle, nil, m.base.nodes[id], m.ours.nodes[id], m.theirs.nodes[id])
+				m.nodes[id].parentID = rootID
+				break
+			}
+			seen[current] = true
+			current = node.parentID
+		}
+	}
+}
+
+func (m *merger) keep(id string, info *nodeInfo) {
+	m.nodes[id] = &mergedNode{props: properties(info.Node), parentID: info.ParentID}
+}
+
+func (m *merger) pick(id string, ours, theirs interface{}) interface{} {
+	if m.resolutions[id] == ResolveTheirs {
+		return theirs
+	}
+	return ours
+}
+
+func (m *merger) conflict(id, kind string, props []string, b, o, t *nodeInfo) {
+	if existing, ok := m.conflicts[id]; ok {
+		existing.Properties = append(existing.Properties, props...)
+		return
+	}
+
+	conflict := &Conflict{NodeID: id, Kind: kind, Properties: props}
+	for _, info := range []*nodeInfo{t, o, b} {
+		if info != nil {
+			conflict.NodeType = info.Type
+		}
+	}
+	if b != nil {
+		conflict.Base = properties(b.Node)
+	}
+	if o != nil {
+		conflict.Ours = properties(o.Node)
+	}
+	if t != nil {
+		conflict.Theirs = properties(t.Node)
+	}
+	m.conflicts[id] = conflict
+}
+
+// build reconstruye el árbol combinado a partir de la raíz
+func (m *merger) build(id string) map[string]interface{} {
+	node := make(map[string]interface{})
+	for key, value := range m.nodes[id].props {
+		node[key] = value
+	}
+	if id != RootID {
+		node[IDKey] = id
+	}
+
+	children := m.childOrder(id)
+	if len(children) > 0 || hasChildren(m.base, id) || hasChildren(m.ours, id) || hasChildren(m.theirs, id) {
+		list := make([]interface{}, 0, len(children))
+		for _, child := range children {
+			list = append(list, m.build(child))
+		}
+		node[ChildrenKey] = list
+	}
+	return node
+}
+
+// childOrder ordena los hijos combinados de un nodo. Se toma como base el orden de la
+// rama que reordenó (si solo una lo hizo) y se insertan los nodos de la otra detrás de
+// su hermano anterior.
+func (m *merger) childOrder(parentID string) []string {
+	members := make(map[string]bool)
+	for id, node := range m.nodes {
+		if node.parentID == parentID && id != parentID {
+			members[id] = true
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	restrict := func(ids []string) []string {
+		var out []string
+		for _, id := range ids {
+			if members[id] {
+				out = append(out, id)
+			}
+		}
+		return out
+	}
+	baseSeq := restrict(m.base.children[parentID])
+	ourSeq := restrict(m.ours.children[parentID])
+	theirSeq := restrict(m.theirs.children[parentID])
+
+	primary, secondary := ourSeq, theirSeq
+	if sameRelativeOrder(baseSeq, ourSeq) && !sameRelativeOrder(baseSeq, theirSeq) {
+		primary, secondary = theirSeq, ourSeq
+	}
+
+	order := append([]string{}, primary...)
+	placed := make(map[string]bool, len(order))
+	for _, id := range order {
+		placed[id] = true
+	}
+
+	for i, id := range secondary {
+		if placed[id] {
+			continue
+		}
+		position := 0
+		for j := i - 1; j >= 0; j-- {
+			if k := indexOf(order, secondary[j]); k >= 0 {
+				position = k + 1
+				break
+			}
+		}
+		order = insertAt(order, position, id)
+		placed[id] = true
+	}
+
+	// Nodos cuyo padre se decidió por resolución y no aparecen en ninguna secuencia
+	var rest []string
+	for id := range members {
+		if !placed[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
+
+// sameRelativeOrder indica si los elementos comunes aparecen en el mismo orden
+func sameRelativeOrder(a, b []string) bool {
+	inB := make(map[string]bool, len(b))
+	for _, id := range b {
+		inB[id] = true
+	}
+	inA := make(map[string]bool, len(a))
+	for _, id := range a {
+		inA[id] = true
+	}
+
+	var commonA, commonB []string
+	for _, id := range a {
+		if inB[id] {
+			commonA = append(commonA, id)
+		}
+	}
+	for _, id := range b {
+		if inA[id] {
+			commonB = append(commonB, id)
+		}
+	}
+	return reflect.DeepEqual(commonA, commonB)
+}
+
+// merge3 combina un valor: gana el lado que lo cambió; si ambos lo cambiaron distinto es conflicto
+func merge3(base, ours, theirs interface{}) (interface{}, bool) {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours, true
+	case reflect.DeepEqual(base, ours):
+		return theirs, true
+	case reflect.DeepEqual(base, theirs):
+		return ours, true
+	}
+	return nil, false
+}
+
+func merge3String(base, ours, theirs string) (string, bool) {
+	switch {
+	case ours == theirs:
+		return ours, true
+	case base == ours:
+		return theirs, true
+	case base == theirs:
+		return ours, true
+	}
+	return "", false
+}
+
+// properties retorna una copia del nodo sin id ni hijos
+func properties(node map[string]interface{}) map[string]interface{} {
+	props := make(map[string]interface{}, len(node))
+	for key, value := range node {
+		if isProperty(key) {
+			props[key] = value
+		}
+	}
+	return props
+}
+
+func rootInfo(idx *treeIndex, info *nodeInfo) *nodeInfo {
+	if info != nil {
+		return info
+	}
+	// La raíz pudo cambiar de ID entre versiones; se usa la raíz del documento
+	for _, candidate := range idx.nodes {
+		if candidate.Index < 0 {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func hasChildren(idx *treeIndex, id string) bool {
+	info, ok := idx.nodes[id]
+	if !ok {
+		return false
+	}
+	_, ok = info.Node[ChildrenKey]
+	return ok
+}
+
+func keysOf[T any](m map[string]T) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for key := range m {
+		keys[key] = true
+	}
+	return keys
+}
@@ -0,0 +1,71 @@
+package content
+
+import (
+	"reflect"
+	"testing"
+)
+
+func text(id, value string) map[string]interface{} {
+	return map[string]interface{}{IDKey: id, TypeKey: "Text", "text": value}
+}
+
+func screen(children ...interface{}) map[string]interface{} {
+	return map[string]interface{}{IDKey: "root", TypeKey: "Scaffold", ChildrenKey: children}
+}
+
+// Los cambios de cada lado se combinan y los hermanos agregados quedan detrás del anterior
+func TestMergeCombinesIndependentChanges(t *testing.T) {
+	base := screen(text("a", "a"), text("b", "b"))
+	ours := screen(text("a", "A"), text("o", "o"), text("b", "b"))
+	theirs := screen(text("a", "a"), text("b", "B"), text("t", "t"))
+
+	result := Merge(base, ours, theirs, nil)
+	if len(result.Conflicts) > 0 {
+		t.Fatalf("conflictos inesperados: %+v", result.Conflicts)
+	}
+	want := screen(text("a", "A"), text("o", "o"), text("b", "B"), text("t", "t"))
+	if !reflect.DeepEqual(result.Document, want) {
+		t.Fatalf("se esperaba %v, se obtuvo %v", want, result.Document)
+	}
+}
+
+// Una propiedad cambiada distinto en ambos lados es conflicto hasta que se resuelve
+func TestMergePropertyConflict(t *testing.T) {
+	base := screen(text("a", "a"))
+	ours := screen(text("a", "ours"))
+	theirs := screen(text("a", "theirs"))
+
+	result := Merge(base, ours, theirs, nil)
+	if result.Document != nil || len(result.Conflicts) != 1 ||
+		result.Conflicts[0].Kind != ConflictProperty || !reflect.DeepEqual(result.Conflicts[0].Properties, []string{"text"}) {
+		t.Fatalf("se esperaba un conflicto en text, se obtuvo %+v", result.Conflicts)
+	}
+
+	for resolution, want := range map[string]string{ResolveOurs: "ours", ResolveTheirs: "theirs"} {
+		result := Merge(base, ours, theirs, map[string]string{"a": resolution})
+		if !reflect.DeepEqual(result.Document, screen(text("a", want))) {
+			t.Errorf("%s: se obtuvo %v", resolution, result.Document)
+		}
+	}
+}
+
+// Eliminar un nodo que el otro lado no tocó se acepta; si lo cambió es conflicto
+func TestMergeDeletedNode(t *testing.T) {
+	base := screen(text("a", "a"), text("b", "b"))
+	ours := screen(text("b", "b"))
+
+	result := Merge(base, ours, base, nil)
+	if !reflect.DeepEqual(result.Document, screen(text("b", "b"))) {
+		t.Fatalf("se esperaba aceptar la eliminación, se obtuvo %v %+v", result.Document, result.Conflicts)
+	}
+
+	theirs := screen(text("a", "cambiado"), text("b", "b"))
+	result = Merge(base, ours, theirs, nil)
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Kind != ConflictDeleteModify || result.Conflicts[0].Ours != nil {
+		t.Fatalf("se esperaba un conflicto delete_modify, se obtuvo %+v", result.Conflicts)
+	}
+	result = Merge(base, ours, theirs, map[string]string{"a": ResolveTheirs})
+	if !reflect.DeepEqual(result.Document, theirs) {
+		t.Fatalf("resolver theirs debía conservar el nodo, se obtuvo %v", result.Document)
+	}
+}
@@ -0,0 +1,217 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tipos de operación de edición sobre el árbol
+const (
+	OpInsertNode = "insert_node"
+	OpDeleteNode = "delete_node"
+	OpMoveNode   = "move_node"
+	OpSetProp    = "set_prop"
+)
+
+var (
+	ErrNodeNotFound     = errors.New("node not found")
+	ErrDuplicateNode    = errors.New("a node with this id already exists")
+	ErrInvalidOperation = errors.New("invalid operation")
+)
+
+// Operation es una edición sobre el árbol de widgets. Los campos usados dependen del tipo:
+//
+//	insert_node: NodeID, ParentID, Index, Node
+//	delete_node: NodeID
+//	move_node:   NodeID, ParentID, Index
+//	set_prop:    NodeID, Key, Value (null elimina la propiedad)
+type Operation struct {
+	Type     string                 `json:"type"`
+	NodeID   string                 `json:"node_id"`
+	ParentID string                 `json:"parent_id,omitempty"`
+	Index    int                    `json:"index"` // Posición entre los hijos; negativa o fuera de rango agrega al final
+	Node     map[string]interface{} `json:"node,omitempty"`
+	Key      string                 `json:"key,omitempty"`
+	Value    interface{}            `json:"value,omitempty"`
+}
+
+// Apply aplica la operación sobre doc, modificándolo, y retorna las posiciones que cambió.
+// Si la operación no es válida para el documento actual retorna un error y doc no cambia.
+func Apply(doc map[string]interface{}, op Operation) (Effect, error) {
+	idx := newTreeIndex(doc)
+	var effect Effect
+
+	switch op.Type {
+	case OpInsertNode:
+		if op.NodeID == "" || op.Node == nil {
+			return effect, fmt.Errorf("%w: insert_node requires node_id and node", ErrInvalidOperation)
+		}
+		if id, ok := op.Node[IDKey]; ok && id != op.NodeID {
+			return effect, fmt.Errorf("%w: node.id must match node_id", ErrInvalidOperation)
+		}
+		parent := lookupNode(idx, doc, op.ParentID)
+		if parent == nil {
+			return effect, ErrNodeNotFound
+		}
+		node := cloneValue(op.Node).(map[string]interface{})
+		node[IDKey] = op.NodeID
+		for id := range newTreeIndex(node).nodes {
+			if _, exists := idx.nodes[id]; exists {
+				return effect, ErrDuplicateNode
+			}
+		}
+		effect.Inserted = &Position{ParentID: positionParent(parent), Index: insertChild(parent.Node, node, op.Index)}
+		effect.NodeID = op.NodeID
+
+	case OpDeleteNode:
+		info := idx.nodes[op.NodeID]
+		if info == nil {
+			return effect, ErrNodeNotFound
+		}
+		if info.Index < 0 {
+			return effect, fmt.Errorf("%w: the root cannot be deleted", ErrInvalidOperation)
+		}
+		parent := idx.nodes[info.ParentID]
+		removeChild(parent.Node, info.Index)
+		effect.Removed = &Position{ParentID: positionParent(parent), Index: info.Index}
+		effect.NodeID = op.NodeID
+		for id := range newTreeIndex(info.Node).nodes {
+			effect.Deleted = append(effect.Deleted, id)
+		}
+
+	case OpMoveNode:
+		info := idx.nodes[op.NodeID]
+		if info == nil {
+			return effect, ErrNodeNotFound
+		}
+		if info.Index < 0 {
+			return effect, fmt.Errorf("%w: the root cannot be moved", ErrInvalidOperation)
+		}
+		parent := lookupNode(idx, doc, op.ParentID)
+		if parent == nil {
+			return effect, ErrNodeNotFound
+		}
+		if parent.ID == op.NodeID || idx.isDescendant(parent.ID, op.NodeID) {
+			return effect, fmt.Errorf("%w: a node cannot be moved inside itself", ErrInvalidOperation)
+		}
+		from := idx.nodes[info.ParentID]
+		removeChild(from.Node, info.Index)
+		effect.Removed = &Position{ParentID: positionParent(from), Index: info.Index}
+		effect.Inserted = &Position{ParentID: positionParent(parent), Index: insertChild(parent.Node, info.Node, op.Index)}
+		effect.NodeID = op.NodeID
+
+	case OpSetProp:
+		info := lookupNode(idx, doc, op.NodeID)
+		if info == nil {
+			return effect, ErrNodeNotFound
+		}
+		if op.Key == "" || !isProperty(op.Key) {
+			return effect, fmt.Errorf("%w: %q is not an editable property", ErrInvalidOperation, op.Key)
+		}
+		if op.Value == nil {
+			delete(info.Node, op.Key)
+		} else {
+			info.Node[op.Key] = cloneValue(op.Value)
+		}
+
+	default:
+		return effect, fmt.Errorf("%w: unknown type %q", ErrInvalidOperation, op.Type)
+	}
+	return effect, nil
+}
+
+// Invert retorna la operación que deshace op, calculada sobre doc antes de aplicarla. La
+// inversa vale para el documento que queda justo después de aplicar op.
+func Invert(doc map[string]interface{}, op Operation) (Operation, error) {
+	idx := newTreeIndex(doc)
+
+	switch op.Type {
+	case OpInsertNode:
+		return Operation{Type: OpDeleteNode, NodeID: op.NodeID}, nil
+
+	case OpDeleteNode, OpMoveNode:
+		info := idx.nodes[op.NodeID]
+		if info == nil {
+			return Operation{}, ErrNodeNotFound
+		}
+		if info.Index < 0 {
+			return Operation{}, fmt.Errorf("%w: the root cannot be deleted or moved", ErrInvalidOperation)
+		}
+		// El nodo vuelve a la posición que tenía, entre los hermanos que quedan sin él
+		inverse := Operation{Type: OpMoveNode, NodeID: op.NodeID, ParentID: positionParent(idx.nodes[info.ParentID]), Index: info.Index}
+		if op.Type == OpDeleteNode {
+			inverse.Type = OpInsertNode
+			inverse.Node = cloneValue(info.Node).(map[string]interface{})
+		}
+		return inverse, nil
+
+	case OpSetProp:
+		info := lookupNode(idx, doc, op.NodeID)
+		if info == nil {
+			return Operation{}, ErrNodeNotFound
+		}
+		// Si la propiedad no existía, la inversa la elimina (Value nil)
+		return Operation{Type: OpSetProp, NodeID: op.NodeID, Key: op.Key, Value: cloneValue(info.Node[op.Key])}, nil
+
+	default:
+		return Operation{}, fmt.Errorf("%w: unknown type %q", ErrInvalidOperation, op.Type)
+	}
+}
+
+// lookupNode busca un nodo por ID; RootID siempre se refiere a la raíz
+func lookupNode(idx *treeIndex, doc map[string]interface{}, id string) *nodeInfo {
+	if id == RootID {
+		return idx.nodes[nodeID(doc)]
+	}
+	return idx.nodes[id]
+}
+
+// positionParent identifica al padre en un Effect; la raíz siempre es RootID
+func positionParent(parent *nodeInfo) string {
+	if parent.Index < 0 {
+		return RootID
+	}
+	return parent.ID
+}
+
+// insertChild agrega child entre los hijos de parent y retorna la posición en que quedó
+func insertChild(parent, child map[string]interface{}, index int) int {
+	children, _ := parent[ChildrenKey].([]interface{})
+	if index < 0 || index > len(children) {
+		index = len(children)
+	}
+	updated := make([]interface{}, 0, len(children)+1)
+	updated = append(updated, children[:index]...)
+	updated = append(updated, child)
+	updated = append(updated, children[index:]...)
+	parent[ChildrenKey] = updated
+	return index
+}
+
+func removeChild(parent map[string]interface{}, index int) {
+	children, _ := parent[ChildrenKey].([]interface{})
+	updated := make([]interface{}, 0, len(children)-1)
+	updated = append(updated, children[:index]...)
+	updated = append(updated, children[index+1:]...)
+	parent[ChildrenKey] = updated
+}
+
+// cloneValue copia un valor JSON para que el documento no comparta estructuras con la operación
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = cloneValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = cloneValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
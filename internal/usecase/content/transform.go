@@ -0,0 +1,137 @@
+package content
+
+// Position es la ubicación de un nodo entre los hijos de su padre
+type Position struct {
+	ParentID string `json:"parent_id"`
+	Index    int    `json:"index"`
+}
+
+// Effect describe lo que una operación aplicada cambió en el árbol. Es lo que se necesita
+// para transformar las operaciones concurrentes que todavía no la conocían.
+type Effect struct {
+	Removed  *Position `json:"removed,omitempty"`  // Posición de la que salió un nodo (delete, move)
+	Inserted *Position `json:"inserted,omitempty"` // Posición en la que entró un nodo (insert, move)
+	Deleted  []string  `json:"deleted,omitempty"`  // IDs del subárbol eliminado
+	NodeID   string    `json:"node_id,omitempty"`  // Nodo insertado, eliminado o movido; vacío en set_prop
+}
+
+// Transform adapta op, creada sin conocer la operación que produjo applied, para que se
+// pueda aplicar después de ella. Retorna false si op ya no tiene sentido porque su nodo
+// o su destino fueron eliminados.
+//
+// Los índices de insert_node y move_node se corren según los hijos que entraron o salieron
+// antes de su posición. Si dos nodos se insertan en la misma posición, el que el servidor
+// aplicó primero queda antes. Las demás diferencias (dos set_prop sobre la misma clave,
+// dos move del mismo nodo) se resuelven por el orden del servidor: gana la última aplicada.
+//
+// op debe nombrar a la raíz con RootID, como los Effect; Rebase lo hace con Normalize.
+func Transform(op Operation, applied Effect) (Operation, bool) {
+	for _, id := range applied.Deleted {
+		if op.NodeID == id {
+			return op, false
+		}
+		if (op.Type == OpInsertNode || op.Type == OpMoveNode) && op.ParentID == id {
+			return op, false
+		}
+	}
+
+	if (op.Type != OpInsertNode && op.Type != OpMoveNode) || op.Index < 0 {
+		return op, true
+	}
+	if r := applied.Removed; r != nil && r.ParentID == op.ParentID && r.Index < op.Index {
+		op.Index--
+	}
+	if i := applied.Inserted; i != nil && i.ParentID == op.ParentID && i.Index <= op.Index {
+		op.Index++
+	}
+	return op, true
+}
+
+// Normalize escribe la raíz de doc como RootID en el destino de op, que el cliente puede
+// nombrar también por su id real
+func Normalize(doc map[string]interface{}, op Operation) Operation {
+	if (op.Type == OpInsertNode || op.Type == OpMoveNode) && op.ParentID != RootID && op.ParentID == nodeID(doc) {
+		op.ParentID = RootID
+	}
+	return op
+}
+
+// Rebase transforma op, creada antes de que se aplicaran las operaciones de applied (en
+// orden), para aplicarla sobre doc tal como quedó después de ellas. Retorna false si alguna
+// la dejó sin efecto.
+//
+// El índice de move_node cuenta los hermanos sin el nodo movido, así que los efectos se
+// pasan a esa numeración siguiendo dónde estaba el nodo antes de cada uno.
+func Rebase(doc map[string]interface{}, op Operation, applied []Effect) (Operation, bool) {
+	op = Normalize(doc, op)
+	var trail []Position
+	if op.Type == OpMoveNode {
+		trail = nodeTrail(doc, op.NodeID, applied)
+	}
+	for i, effect := range applied {
+		if trail != nil {
+			effect = withoutNode(effect, op.NodeID, trail[i])
+		}
+		var ok bool
+		if op, ok = Transform(op, effect); !ok {
+			return op, false
+		}
+	}
+	return op, true
+}
+
+// nodeTrail retorna la posición del nodo antes de cada efecto, recorriéndolos hacia atrás
+// desde su posición en doc. Index -1 indica que el nodo no estaba en el árbol.
+func nodeTrail(doc map[string]interface{}, nodeID string, applied []Effect) []Position {
+	idx := newTreeIndex(doc)
+	info := idx.nodes[nodeID]
+	if info == nil || info.Index < 0 {
+		return nil
+	}
+	at := Position{ParentID: positionParent(idx.nodes[info.ParentID]), Index: info.Index}
+
+	trail := make([]Position, len(applied))
+	for i := len(applied) - 1; i >= 0; i-- {
+		effect := applied[i]
+		switch {
+		case at.Index < 0:
+		case effect.NodeID == nodeID && effect.Removed != nil:
+			at = *effect.Removed
+		case effect.NodeID == nodeID:
+			// Lo insertó este efecto
+			at = Position{Index: -1}
+		default:
+			if in := effect.Inserted; in != nil && in.ParentID == at.ParentID && in.Index < at.Index {
+				at.Index--
+			}
+			if out := effect.Removed; out != nil && out.ParentID == at.ParentID && out.Index <= at.Index {
+				at.Index++
+			}
+		}
+		trail[i] = at
+	}
+	return trail
+}
+
+// withoutNode numera las posiciones del efecto como si el nodo, que estaba en at, no
+// estuviera entre sus hermanos
+func withoutNode(effect Effect, nodeID string, at Position) Effect {
+	if effect.NodeID == nodeID {
+		// Otro movimiento del mismo nodo: la lista sin él no cambió
+		return Effect{Deleted: effect.Deleted, NodeID: effect.NodeID}
+	}
+	if at.Index < 0 {
+		return effect
+	}
+	if out := effect.Removed; out != nil && out.ParentID == at.ParentID {
+		if out.Index > at.Index {
+			effect.Removed = &Position{ParentID: out.ParentID, Index: out.Index - 1}
+		} else {
+			at.Index--
+		}
+	}
+	if in := effect.Inserted; in != nil && in.ParentID == at.ParentID && in.Index > at.Index {
+		effect.Inserted = &Position{ParentID: in.ParentID, Index: in.Index - 1}
+	}
+	return effect
+}
@@ -0,0 +1,242 @@
+package content
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// simClient es un cliente simulado: una copia del documento en base y las operaciones del
+// servidor que todavía no aplicó
+type simClient struct {
+	doc  map[string]interface{}
+	base int
+}
+
+// simServer aplica las operaciones en el orden en que llegan, como la sala
+type simServer struct {
+	doc       map[string]interface{}
+	effects   []Effect    // effects[i] es el de la operación seq i+1
+	broadcast []Operation // Operaciones rebasadas que reciben los clientes
+	touched   []map[string]bool
+}
+
+func TestRebaseConvergesWithManyClients(t *testing.T) {
+	for seed := int64(1); seed <= 300; seed++ {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			runSimulation(t, rand.New(rand.NewSource(seed)))
+		})
+	}
+}
+
+func runSimulation(t *testing.T, rng *rand.Rand) {
+	server := &simServer{doc: randomDocument(rng)}
+	clients := make([]*simClient, 2+rng.Intn(5))
+	for i := range clients {
+		clients[i] = &simClient{doc: cloneDoc(server.doc)}
+	}
+
+	nextID := 0
+	for step := 0; step < 60; step++ {
+		client := clients[rng.Intn(len(clients))]
+		if rng.Intn(4) == 0 {
+			catchUp(t, client, server)
+		}
+
+		nextID++
+		op, ok := randomOperation(rng, client.doc, fmt.Sprintf("n%d", nextID))
+		if !ok {
+			continue
+		}
+		server.submit(t, client, op)
+	}
+
+	for i, client := range clients {
+		catchUp(t, client, server)
+		if !reflect.DeepEqual(client.doc, server.doc) {
+			t.Fatalf("el cliente %d no converge:\ncliente  %v\nservidor %v", i, client.doc, server.doc)
+		}
+	}
+}
+
+// submit rebasa la operación que el cliente creó sobre su copia, la aplica y verifica que
+// respete la intención del cliente
+func (s *simServer) submit(t *testing.T, client *simClient, op Operation) {
+	t.Helper()
+	rebased, ok := Rebase(s.doc, op, s.effects[client.base:])
+	if !ok {
+		return
+	}
+	effect, err := Apply(s.doc, rebased)
+	if err != nil {
+		// Un ciclo creado por movimientos concurrentes; la sala la rechaza igual
+		return
+	}
+
+	touched := map[string]bool{}
+	for _, id := range effect.Deleted {
+		touched[id] = true
+	}
+	if op.Type == OpMoveNode {
+		touched[op.NodeID] = true
+	}
+	s.effects = append(s.effects, effect)
+	s.broadcast = append(s.broadcast, rebased)
+	s.touched = append(s.touched, touched)
+
+	checkIntention(t, client, s, op)
+}
+
+// checkIntention verifica que un nodo insertado o movido quede entre los mismos vecinos que
+// tenía en la copia del cliente, salvo los que otra operación concurrente movió o eliminó
+func checkIntention(t *testing.T, client *simClient, s *simServer, op Operation) {
+	t.Helper()
+	if op.Type != OpInsertNode && op.Type != OpMoveNode {
+		return
+	}
+
+	moved := map[string]bool{}
+	for _, touched := range s.touched[client.base : len(s.touched)-1] {
+		for id := range touched {
+			moved[id] = true
+		}
+	}
+
+	before := childIDs(client.doc, op.ParentID, op.NodeID)
+	index := op.Index
+	if index < 0 || index > len(before) {
+		index = len(before)
+	}
+	after := childIDs(s.doc, op.ParentID, "")
+	position := indexOf(after, op.NodeID)
+	if position < 0 {
+		t.Fatalf("%s de %s no quedó en %q: %v", op.Type, op.NodeID, op.ParentID, after)
+	}
+
+	for i, id := range before {
+		if moved[id] {
+			continue
+		}
+		at := indexOf(after, id)
+		if at < 0 {
+			continue
+		}
+		if (i < index && at > position) || (i >= index && at < position) {
+			t.Fatalf("%s de %s en %q[%d] quedó mal ubicado respecto de %s\ncliente  %v\nservidor %v",
+				op.Type, op.NodeID, op.ParentID, op.Index, id, before, after)
+		}
+	}
+}
+
+// catchUp aplica en la copia del cliente las operaciones del servidor que le faltan
+func catchUp(t *testing.T, client *simClient, server *simServer) {
+	t.Helper()
+	for ; client.base < len(server.broadcast); client.base++ {
+		if _, err := Apply(client.doc, server.broadcast[client.base]); err != nil {
+			t.Fatalf("la operación %d no se pudo aplicar en el cliente: %v", client.base+1, err)
+		}
+	}
+}
+
+// randomDocument arma un árbol chico cuya raíz tiene id propio, para que los clientes la
+// nombren tanto por "root" como por RootID
+func randomDocument(rng *rand.Rand) map[string]interface{} {
+	doc := map[string]interface{}{IDKey: "root", TypeKey: "Scaffold", ChildrenKey: []interface{}{}}
+	nodes := []map[string]interface{}{doc}
+	for i := 0; i < 6+rng.Intn(6); i++ {
+		parent := nodes[rng.Intn(len(nodes))]
+		node := map[string]interface{}{IDKey: fmt.Sprintf("d%d", i), TypeKey: "Container", ChildrenKey: []interface{}{}}
+		parent[ChildrenKey] = append(parent[ChildrenKey].([]interface{}), node)
+		nodes = append(nodes, node)
+	}
+	return doc
+}
+
+// randomOperation crea una operación válida sobre doc
+func randomOperation(rng *rand.Rand, doc map[string]interface{}, newID string) (Operation, bool) {
+	idx := newTreeIndex(doc)
+	ids := make([]string, 0, len(idx.nodes))
+	for id := range idx.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	pick := func() *nodeInfo { return idx.nodes[ids[rng.Intn(len(ids))]] }
+	parentID := func(info *nodeInfo) string {
+		if info.Index < 0 && rng.Intn(2) == 0 {
+			return RootID
+		}
+		return info.ID
+	}
+
+	switch rng.Intn(4) {
+	case 0:
+		parent := pick()
+		return Operation{
+			Type:     OpInsertNode,
+			NodeID:   newID,
+			ParentID: parentID(parent),
+			Index:    rng.Intn(len(childIDs(doc, parent.ID, "")) + 1),
+			Node:     map[string]interface{}{TypeKey: "Text", ChildrenKey: []interface{}{}},
+		}, true
+
+	case 1:
+		node := pick()
+		if node.Index < 0 {
+			return Operation{}, false
+		}
+		return Operation{Type: OpDeleteNode, NodeID: node.ID}, true
+
+	case 2:
+		node, parent := pick(), pick()
+		if node.Index < 0 || parent.ID == node.ID || idx.isDescendant(parent.ID, node.ID) {
+			return Operation{}, false
+		}
+		return Operation{
+			Type:     OpMoveNode,
+			NodeID:   node.ID,
+			ParentID: parentID(parent),
+			Index:    rng.Intn(len(childIDs(doc, parent.ID, node.ID)) + 1),
+		}, true
+
+	default:
+		return Operation{Type: OpSetProp, NodeID: pick().ID, Key: "color", Value: fmt.Sprint(rng.Intn(5))}, true
+	}
+}
+
+// childIDs lista los hijos del nodo, sin skip
+func childIDs(doc map[string]interface{}, parentID, skip string) []string {
+	idx := newTreeIndex(doc)
+	parent := lookupNode(idx, doc, parentID)
+	if parent == nil {
+		return nil
+	}
+	var ids []string
+	for _, id := range idx.children[parent.ID] {
+		if id != skip {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func cloneDoc(doc map[string]interface{}) map[string]interface{} {
+	return cloneValue(doc).(map[string]interface{})
+}
+
+func TestRebaseNormalizesRootID(t *testing.T) {
+	doc := map[string]interface{}{IDKey: "root", ChildrenKey: []interface{}{
+		map[string]interface{}{IDKey: "a"},
+		map[string]interface{}{IDKey: "b"},
+	}}
+	deleted := Effect{Removed: &Position{ParentID: RootID, Index: 0}, Deleted: []string{"a"}}
+
+	for _, parent := range []string{RootID, "root"} {
+		op := Operation{Type: OpInsertNode, NodeID: "c", ParentID: parent, Index: 2}
+		rebased, ok := Rebase(doc, op, []Effect{deleted})
+		if !ok || rebased.Index != 1 || rebased.ParentID != RootID {
+			t.Errorf("parent %q: se esperaba index 1 en la raíz, se obtuvo %+v (%v)", parent, rebased, ok)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+type ChatRepository interface {
+	Create(message *entity.ChatMessage) error
+	FindByID(projectID, id string) (*entity.ChatMessage, error)
+	// FindByProject retorna hasta limit mensajes anteriores a before (ID de mensaje, "" = los últimos),
+	// del más nuevo al más viejo
+	FindByProject(projectID, before string, limit int) ([]entity.ChatMessage, error)
+	Update(message *entity.ChatMessage) error
+	Delete(message *entity.ChatMessage) error
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type ChatRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewChatRepository(db *gorm.DB) ChatRepository {
+	return &ChatRepositoryImpl{db: db}
+}
+
+func (r *ChatRepositoryImpl) Create(message *entity.ChatMessage) error {
+	return r.db.Create(message).Error
+}
+
+func (r *ChatRepositoryImpl) FindByID(projectID, id string) (*entity.ChatMessage, error) {
+	var message entity.ChatMessage
+	err := r.db.First(&message, "id = ? AND project_id = ?", id, projectID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *ChatRepositoryImpl) FindByProject(projectID, before string, limit int) ([]entity.ChatMessage, error) {
+	query := r.db.Where("project_id = ?", projectID)
+	if before != "" {
+		// Paginación por cursor: (created_at, id) desempata mensajes del mismo instante
+		query = query.Where("(created_at, id) < (SELECT created_at, id FROM chat_messages WHERE id = ?)", before)
+	}
+
+	var messages []entity.ChatMessage
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *ChatRepositoryImpl) Update(message *entity.ChatMessage) error {
+	return r.db.Save(message).Error
+}
+
+func (r *ChatRepositoryImpl) Delete(message *entity.ChatMessage) error {
+	return r.db.Delete(message).Error
+}
@@ -0,0 +1,24 @@
+package repositories
+
+// Operaciones soportadas por ProjectRepository.MutateContent
+const (
+	ContentSet    = "set"
+	ContentDelete = "delete"
+	ContentInsert = "insert"
+)
+
+// ContentMutation describe un cambio sobre un subárbol de Project.Content que se
+// aplica directamente en Postgres con las funciones jsonb
+type ContentMutation struct {
+	Op    string
+	Path  []string // Segmentos del JSON Pointer (sin escapar)
+	Value []byte   // JSON del valor a escribir o del nodo a insertar
+
+	// Index es la posición del nuevo hijo para ContentInsert; negativo agrega al final
+	Index int
+	// NodeIDs son los "id" de los nodos que escriben ContentInsert y ContentSet; no pueden
+	// existir en el resto del documento
+	NodeIDs []string
+	// ExpectedRevision hace fallar la operación si la revisión actual es otra (0 = sin verificar)
+	ExpectedRevision int
+}
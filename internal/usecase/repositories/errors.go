@@ -0,0 +1,9 @@
+package repositories
+
+import "errors"
+
+var (
+	ErrContentPathNotFound = errors.New("content path not found")
+	ErrRevisionConflict    = errors.New("project revision has changed")
+	ErrDuplicateNodeID     = errors.New("a node with this id already exists")
+)
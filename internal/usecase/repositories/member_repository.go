@@ -0,0 +1,11 @@
+package repositories
+
+import "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+type MemberRepository interface {
+	Save(member *entity.ProjectMember) error
+	Find(projectID, userID string) (*entity.ProjectMember, error)
+	FindByProject(projectID string) ([]entity.ProjectMember, error)
+	FindProjectIDsByUser(userID string) ([]string, error)
+	Delete(projectID, userID string) error
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type MemberRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewMemberRepository(db *gorm.DB) MemberRepository {
+	return &MemberRepositoryImpl{db: db}
+}
+
+func (r *MemberRepositoryImpl) Save(member *entity.ProjectMember) error {
+	return r.db.Omit("User").Save(member).Error
+}
+
+func (r *MemberRepositoryImpl) Find(projectID, userID string) (*entity.ProjectMember, error) {
+	var member entity.ProjectMember
+	err := r.db.First(&member, "project_id = ? AND user_id = ?", projectID, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *MemberRepositoryImpl) FindByProject(projectID string) ([]entity.ProjectMember, error) {
+	var members []entity.ProjectMember
+	err := r.db.Preload("User").Where("project_id = ?", projectID).Find(&members).Error
+	return members, err
+}
+
+func (r *MemberRepositoryImpl) FindProjectIDsByUser(userID string) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&entity.ProjectMember{}).
+		Where("user_id = ?", userID).
+		Pluck("project_id", &ids).Error
+	return ids, err
+}
+
+func (r *MemberRepositoryImpl) Delete(projectID, userID string) error {
+	result := r.db.Delete(&entity.ProjectMember{}, "project_id = ? AND user_id = ?", projectID, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
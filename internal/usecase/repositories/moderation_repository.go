@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+)
+
+type ModerationRepository interface {
+	CreateSanction(sanction *entity.RoomSanction) error
+	// FindActiveSanction retorna la sanción del tipo dado vigente en now; gorm.ErrRecordNotFound si no hay
+	FindActiveSanction(projectID, userID, kind string, now time.Time) (*entity.RoomSanction, error)
+	// LiftSanctions levanta las sanciones del tipo dado y retorna cuántas había
+	LiftSanctions(projectID, userID, kind string) (int64, error)
+	CreateLog(entry *entity.ModerationLog) error
+	// FindLogs retorna las últimas acciones de moderación del proyecto, de la más nueva a la más vieja
+	FindLogs(projectID string, limit int) ([]entity.ModerationLog, error)
+}
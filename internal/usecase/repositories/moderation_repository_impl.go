@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type ModerationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) ModerationRepository {
+	return &ModerationRepositoryImpl{db: db}
+}
+
+func (r *ModerationRepositoryImpl) CreateSanction(sanction *entity.RoomSanction) error {
+	return r.db.Create(sanction).Error
+}
+
+func (r *ModerationRepositoryImpl) FindActiveSanction(projectID, userID, kind string, now time.Time) (*entity.RoomSanction, error) {
+	var sanction entity.RoomSanction
+	err := r.db.
+		Where("project_id = ? AND user_id = ? AND kind = ?", projectID, userID, kind).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at DESC").
+		First(&sanction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sanction, nil
+}
+
+func (r *ModerationRepositoryImpl) LiftSanctions(projectID, userID, kind string) (int64, error) {
+	result := r.db.
+		Where("project_id = ? AND user_id = ? AND kind = ?", projectID, userID, kind).
+		Delete(&entity.RoomSanction{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *ModerationRepositoryImpl) CreateLog(entry *entity.ModerationLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *ModerationRepositoryImpl) FindLogs(projectID string, limit int) ([]entity.ModerationLog, error) {
+	var entries []entity.ModerationLog
+	err := r.db.Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
@@ -8,4 +8,15 @@ type ProjectRepository interface {
 	FindAll() ([]entity.Project, error)
 	Update(project *entity.Project) error
 	Delete(id string) error
+	FindIDsByOwner(ownerID string) ([]string, error)
+	FindByParent(parentID string) ([]entity.Project, error)
+	UpdateMergeBase(id string, baseRevision, mergedRevision int) error
+
+	// FindContent lee solo el subárbol del Content en path
+	FindContent(projectID string, path []string) ([]byte, int, error)
+	// MutateContent modifica un subárbol del Content en Postgres, incrementa la revisión y registra la versión
+	MutateContent(projectID string, mutation ContentMutation) (*entity.Project, error)
+
+	FindVersion(projectID string, revision int) (*entity.ProjectVersion, error)
+	FindVersions(projectID string) ([]entity.ProjectVersion, error)
 }
@@ -1,9 +1,16 @@
 package repositories
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProjectRepositoryImpl struct {
@@ -15,7 +22,13 @@ func NewProjectRepository(db *gorm.DB) ProjectRepository {
 }
 
 func (r *ProjectRepositoryImpl) Create(project *entity.Project) error {
-	return r.db.Create(project).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		project.Revision = 1
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return tx.Create(newProjectVersion(project)).Error
+	})
 }
 
 func (r *ProjectRepositoryImpl) FindByID(id string) (*entity.Project, error) {
@@ -33,10 +46,240 @@ func (r *ProjectRepositoryImpl) FindAll() ([]entity.Project, error) {
 	return projects, err
 }
 
+// Update guarda el proyecto con la siguiente revisión y registra su versión
 func (r *ProjectRepositoryImpl) Update(project *entity.Project) error {
-	return r.db.Save(project).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current entity.Project
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "id = ?", project.ID).Error
+		if err != nil {
+			return err
+		}
+
+		project.Revision = current.Revision + 1
+		project.CreatedAt = current.CreatedAt
+		project.ParentID = current.ParentID
+		project.BranchName = current.BranchName
+		project.BaseRevision = current.BaseRevision
+		project.MergedRevision = current.MergedRevision
+		if err := tx.Save(project).Error; err != nil {
+			return err
+		}
+		return tx.Create(newProjectVersion(project)).Error
+	})
 }
 
 func (r *ProjectRepositoryImpl) Delete(id string) error {
 	return r.db.Delete(&entity.Project{}, "id = ?", id).Error
 }
+
+func (r *ProjectRepositoryImpl) FindByParent(parentID string) ([]entity.Project, error) {
+	var projects []entity.Project
+	err := r.db.Where("parent_id = ?", parentID).Order("created_at").Find(&projects).Error
+	return projects, err
+}
+
+// UpdateMergeBase mueve el ancestro común de una rama sin crear una nueva revisión
+func (r *ProjectRepositoryImpl) UpdateMergeBase(id string, baseRevision, mergedRevision int) error {
+	return r.db.Model(&entity.Project{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"base_revision":   baseRevision,
+		"merged_revision": mergedRevision,
+	}).Error
+}
+
+func (r *ProjectRepositoryImpl) FindIDsByOwner(ownerID string) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&entity.Project{}).Where("owner_id = ?", ownerID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *ProjectRepositoryImpl) FindVersion(projectID string, revision int) (*entity.ProjectVersion, error) {
+	var version entity.ProjectVersion
+	err := r.db.First(&version, "project_id = ? AND revision = ?", projectID, revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *ProjectRepositoryImpl) FindVersions(projectID string) ([]entity.ProjectVersion, error) {
+	var versions []entity.ProjectVersion
+	err := r.db.Omit("content").
+		Where("project_id = ?", projectID).
+		Order("revision DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+func newProjectVersion(project *entity.Project) *entity.ProjectVersion {
+	return &entity.ProjectVersion{
+		ProjectID:   project.ID,
+		Revision:    project.Revision,
+		Title:       project.Title,
+		Description: project.Description,
+		Content:     project.Content,
+	}
+}
+
+func (r *ProjectRepositoryImpl) FindContent(projectID string, path []string) ([]byte, int, error) {
+	var row struct {
+		Value    datatypes.JSON
+		Revision int
+	}
+	result := r.db.Raw(
+		"SELECT content #> ?::text[] AS value, revision FROM projects WHERE id = ? AND deleted_at IS NULL",
+		textArray(path), projectID,
+	).Scan(&row)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, 0, gorm.ErrRecordNotFound
+	}
+	if row.Value == nil {
+		return nil, row.Revision, ErrContentPathNotFound
+	}
+	return row.Value, row.Revision, nil
+}
+
+func (r *ProjectRepositoryImpl) MutateContent(projectID string, mutation ContentMutation) (*entity.Project, error) {
+	expr, guard, args, err := contentMutationSQL(mutation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los parámetros van en el orden del texto: expresión, condición y luego el proyecto
+	query := "UPDATE projects SET content = " + expr +
+		", revision = revision + 1, updated_at = now()" +
+		" WHERE " + guard + " AND id = ? AND deleted_at IS NULL"
+	args = append(args, projectID)
+	if mutation.ExpectedRevision > 0 {
+		query += " AND revision = ?"
+		args = append(args, mutation.ExpectedRevision)
+	}
+	query += " RETURNING *"
+
+	var project entity.Project
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Raw(query, args...).Scan(&project)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return r.mutationFailure(tx, projectID, mutation)
+		}
+		return tx.Create(newProjectVersion(&project)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// nodeIDsPath busca en todo el documento un objeto cuyo "id" sea alguno de $ids. Se pasa
+// como parámetro porque GORM reemplazaría el '?' del filtro jsonpath.
+const nodeIDsPath = "$.** ? (@.id == $ids[*])"
+
+// contentMutationSQL arma la expresión jsonb del nuevo Content y la condición que
+// garantiza que la ruta sea válida antes de escribir
+func contentMutationSQL(m ContentMutation) (expr, guard string, args []interface{}, err error) {
+	path := textArray(m.Path)
+
+	switch m.Op {
+	case ContentSet:
+		if len(m.Path) == 0 {
+			return "?::jsonb", "TRUE", []interface{}{string(m.Value)}, nil
+		}
+		// El padre debe existir; en arreglos solo se reemplazan posiciones existentes
+		parent := textArray(m.Path[:len(m.Path)-1])
+		expr = "jsonb_set(content, ?::text[], ?::jsonb, true)"
+		guard = "(jsonb_typeof(content #> ?::text[]) = 'object' OR (jsonb_typeof(content #> ?::text[]) = 'array' AND content #> ?::text[] IS NOT NULL))"
+		args = []interface{}{path, string(m.Value), parent, parent, path}
+		if len(m.NodeIDs) > 0 {
+			// Los nodos escritos solo pueden repetir ids del valor que reemplazan
+			guard += " AND NOT jsonb_path_exists(content #- ?::text[], ?::jsonpath, jsonb_build_object('ids', ?::jsonb))"
+			args = append(args, path, nodeIDsPath, nodeIDsJSON(m.NodeIDs))
+		}
+		return expr, "(" + guard + ")", args, nil
+
+	case ContentDelete:
+		if len(m.Path) == 0 {
+			return "", "", nil, ErrContentPathNotFound
+		}
+		return "content #- ?::text[]", "content #> ?::text[] IS NOT NULL", []interface{}{path, path}, nil
+
+	case ContentInsert:
+		children := textArray(append(append([]string{}, m.Path...), "children"))
+		expr = `CASE
+			WHEN content #> ?::text[] IS NULL THEN jsonb_set(content, ?::text[], jsonb_build_array(?::jsonb), true)
+			WHEN ? < 0 OR ? >= jsonb_array_length(content #> ?::text[]) THEN jsonb_insert(content, ?::text[] || '-1'::text, ?::jsonb, true)
+			ELSE jsonb_insert(content, ?::text[] || ?::text, ?::jsonb, false)
+		END`
+		guard = `jsonb_typeof(content #> ?::text[]) = 'object'
+			AND (content #> ?::text[] IS NULL OR jsonb_typeof(content #> ?::text[]) = 'array')
+			AND NOT jsonb_path_exists(content, ?::jsonpath, jsonb_build_object('ids', ?::jsonb))`
+		value := string(m.Value)
+		args = []interface{}{
+			children, children, value,
+			m.Index, m.Index, children, children, value,
+			children, strconv.Itoa(m.Index), value,
+			path, children, children, nodeIDsPath, nodeIDsJSON(m.NodeIDs),
+		}
+		return expr, "(" + guard + ")", args, nil
+	}
+
+	return "", "", nil, fmt.Errorf("unknown content operation %q", m.Op)
+}
+
+// mutationFailure explica por qué el UPDATE no modificó ninguna fila
+func (r *ProjectRepositoryImpl) mutationFailure(tx *gorm.DB, projectID string, m ContentMutation) error {
+	// En un set el valor reemplazado no cuenta como duplicado
+	doc, args := "content", []interface{}{}
+	if m.Op == ContentSet {
+		doc, args = "content #- ?::text[]", append(args, textArray(m.Path))
+	}
+	args = append(args, nodeIDsPath, nodeIDsJSON(m.NodeIDs), projectID)
+
+	var state struct {
+		Revision  int
+		Duplicate bool
+	}
+	result := tx.Raw(
+		"SELECT revision, jsonb_path_exists("+doc+", ?::jsonpath, jsonb_build_object('ids', ?::jsonb)) AS duplicate"+
+			" FROM projects WHERE id = ? AND deleted_at IS NULL",
+		args...,
+	).Scan(&state)
+	switch {
+	case result.Error != nil:
+		return result.Error
+	case result.RowsAffected == 0:
+		return gorm.ErrRecordNotFound
+	case m.ExpectedRevision > 0 && state.Revision != m.ExpectedRevision:
+		return ErrRevisionConflict
+	case state.Duplicate:
+		return ErrDuplicateNodeID
+	default:
+		return ErrContentPathNotFound
+	}
+}
+
+// nodeIDsJSON serializa los ids como arreglo JSON; sin ids es un arreglo vacío, que no
+// coincide con ningún nodo
+func nodeIDsJSON(ids []string) string {
+	if len(ids) == 0 {
+		return "[]"
+	}
+	raw, _ := json.Marshal(ids)
+	return string(raw)
+}
+
+// textArray serializa una ruta como literal de arreglo text de Postgres
+func textArray(tokens []string) string {
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, `\`, `\\`)
+		token = strings.ReplaceAll(token, `"`, `\"`)
+		quoted[i] = `"` + token + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
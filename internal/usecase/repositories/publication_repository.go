@@ -0,0 +1,10 @@
+package repositories
+
+import "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+type PublicationRepository interface {
+	Save(publication *entity.ProjectPublication) error
+	FindByProjectID(projectID string) (*entity.ProjectPublication, error)
+	FindBySlug(slug string) (*entity.ProjectPublication, error)
+	SlugExists(slug string) (bool, error)
+}
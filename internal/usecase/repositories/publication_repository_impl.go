@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type PublicationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPublicationRepository(db *gorm.DB) PublicationRepository {
+	return &PublicationRepositoryImpl{db: db}
+}
+
+func (r *PublicationRepositoryImpl) Save(publication *entity.ProjectPublication) error {
+	return r.db.Save(publication).Error
+}
+
+func (r *PublicationRepositoryImpl) FindByProjectID(projectID string) (*entity.ProjectPublication, error) {
+	var publication entity.ProjectPublication
+	err := r.db.First(&publication, "project_id = ?", projectID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &publication, nil
+}
+
+// FindBySlug solo encuentra publicaciones de proyectos que no se eliminaron
+func (r *PublicationRepositoryImpl) FindBySlug(slug string) (*entity.ProjectPublication, error) {
+	var publication entity.ProjectPublication
+	err := r.db.
+		Joins("JOIN projects ON projects.id = project_publications.project_id AND projects.deleted_at IS NULL").
+		First(&publication, "project_publications.slug = ?", slug).Error
+	if err != nil {
+		return nil, err
+	}
+	return &publication, nil
+}
+
+func (r *PublicationRepositoryImpl) SlugExists(slug string) (bool, error) {
+	var count int64
+	err := r.db.Model(&entity.ProjectPublication{}).Where("slug = ?", slug).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+)
+
+type TicketRepository interface {
+	Create(ticket *entity.ConnectionTicket) error
+	// Consume borra el ticket y lo retorna, aunque esté vencido; gorm.ErrRecordNotFound si
+	// no existe o ya se usó
+	Consume(tokenHash string) (*entity.ConnectionTicket, error)
+	// DeleteExpired borra los tickets vencidos en now que nunca se usaron
+	DeleteExpired(now time.Time) error
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type TicketRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewTicketRepository(db *gorm.DB) TicketRepository {
+	return &TicketRepositoryImpl{db: db}
+}
+
+func (r *TicketRepositoryImpl) Create(ticket *entity.ConnectionTicket) error {
+	return r.db.Create(ticket).Error
+}
+
+func (r *TicketRepositoryImpl) Consume(tokenHash string) (*entity.ConnectionTicket, error) {
+	// Borrar y leer en la misma sentencia: si dos instancias canjean a la vez solo una lo obtiene
+	var tickets []entity.ConnectionTicket
+	err := r.db.Raw("DELETE FROM connection_tickets WHERE token_hash = ? RETURNING *", tokenHash).
+		Scan(&tickets).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &tickets[0], nil
+}
+
+func (r *TicketRepositoryImpl) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&entity.ConnectionTicket{}).Error
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+)
+
+type WebhookRepository interface {
+	Create(webhook *entity.Webhook) error
+	FindByID(id string) (*entity.Webhook, error)
+	FindByProject(projectID string) ([]entity.Webhook, error)
+	FindActiveByProject(projectID string) ([]entity.Webhook, error)
+	Delete(id string) error
+
+	CreateDelivery(delivery *entity.WebhookDelivery) error
+	UpdateDelivery(delivery *entity.WebhookDelivery) error
+	FindDelivery(id string) (*entity.WebhookDelivery, error)
+	FindDeliveries(webhookID string, limit int) ([]entity.WebhookDelivery, error)
+	FindDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error)
+}
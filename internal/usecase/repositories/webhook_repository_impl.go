@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &WebhookRepositoryImpl{db: db}
+}
+
+func (r *WebhookRepositoryImpl) Create(webhook *entity.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *WebhookRepositoryImpl) FindByID(id string) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	err := r.db.First(&webhook, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepositoryImpl) FindByProject(projectID string) ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	err := r.db.Where("project_id = ?", projectID).Order("created_at").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepositoryImpl) FindActiveByProject(projectID string) ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	err := r.db.Where("project_id = ? AND active", projectID).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepositoryImpl) Delete(id string) error {
+	return r.db.Delete(&entity.Webhook{}, "id = ?", id).Error
+}
+
+func (r *WebhookRepositoryImpl) CreateDelivery(delivery *entity.WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *WebhookRepositoryImpl) UpdateDelivery(delivery *entity.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+func (r *WebhookRepositoryImpl) FindDelivery(id string) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := r.db.First(&delivery, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookRepositoryImpl) FindDeliveries(webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := r.db.Where("webhook_id = ?", webhookID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindDueDeliveries busca entregas pendientes cuyo próximo intento ya venció
+func (r *WebhookRepositoryImpl) FindDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := r.db.Where("status IN ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)",
+		[]string{entity.WebhookDeliveryPending, entity.WebhookDeliveryRetrying}, now).
+		Order("created_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
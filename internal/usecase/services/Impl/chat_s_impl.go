@@ -0,0 +1,129 @@
+package impl
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultChatPageSize = 50
+	maxChatPageSize     = 100
+)
+
+type ChatServiceImpl struct {
+	repo           repositories.ChatRepository
+	projectService services.ProjectService
+}
+
+func NewChatService(repo repositories.ChatRepository, projectService services.ProjectService) services.ChatService {
+	return &ChatServiceImpl{
+		repo:           repo,
+		projectService: projectService,
+	}
+}
+
+func (s *ChatServiceImpl) SendMessage(projectID string, userID uuid.UUID, username, text string) (*entity.ChatMessage, error) {
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &entity.ChatMessage{
+		ProjectID: pid,
+		UserID:    userID,
+		Username:  username,
+		Text:      text,
+	}
+	if err := s.repo.Create(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *ChatServiceImpl) EditMessage(projectID, messageID string, userID uuid.UUID, text string) (*entity.ChatMessage, error) {
+	message, err := s.ownMessage(projectID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	message.Text = text
+	message.EditedAt = &now
+	if err := s.repo.Update(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *ChatServiceImpl) DeleteMessage(projectID, messageID string, userID uuid.UUID) (*entity.ChatMessage, error) {
+	message, err := s.ownMessage(projectID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Delete(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *ChatServiceImpl) GetHistory(projectID string, userID uuid.UUID, before string, limit int) (*dto.ChatHistoryResponse, error) {
+	role, err := s.projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, services.ErrForbidden
+	}
+
+	if limit <= 0 {
+		limit = defaultChatPageSize
+	}
+	limit = min(limit, maxChatPageSize)
+
+	// Se pide uno más para saber si hay una página anterior
+	messages, err := s.repo.FindByProject(projectID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &dto.ChatHistoryResponse{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		res.NextBefore = messages[limit-1].ID.String()
+	}
+	res.Messages = oldestFirst(messages)
+	return res, nil
+}
+
+func (s *ChatServiceImpl) GetRecent(projectID string, limit int) ([]entity.ChatMessage, error) {
+	messages, err := s.repo.FindByProject(projectID, "", limit)
+	if err != nil {
+		return nil, err
+	}
+	return oldestFirst(messages), nil
+}
+
+// ownMessage carga el mensaje y verifica que sea del usuario
+func (s *ChatServiceImpl) ownMessage(projectID, messageID string, userID uuid.UUID) (*entity.ChatMessage, error) {
+	message, err := s.repo.FindByID(projectID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.UserID != userID {
+		return nil, services.ErrForbidden
+	}
+	return message, nil
+}
+
+func oldestFirst(messages []entity.ChatMessage) []entity.ChatMessage {
+	out := make([]entity.ChatMessage, len(messages))
+	for i, message := range messages {
+		out[len(messages)-1-i] = message
+	}
+	return out
+}
@@ -0,0 +1,312 @@
+package impl
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+)
+
+// maxContentAttempts limita los reintentos cuando un nodo se mueve mientras se lo modifica
+const maxContentAttempts = 3
+
+type ContentServiceImpl struct {
+	projectRepo    repositories.ProjectRepository
+	projectService services.ProjectService
+	events         *event.Bus
+}
+
+func NewContentService(projectRepo repositories.ProjectRepository, projectService services.ProjectService, events *event.Bus) services.ContentService {
+	return &ContentServiceImpl{
+		projectRepo:    projectRepo,
+		projectService: projectService,
+		events:         events,
+	}
+}
+
+func (s *ContentServiceImpl) Get(projectID string, userID uuid.UUID, target dto.ContentTarget) (*dto.ContentResponse, error) {
+	if err := s.authorize(projectID, userID, false); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		path, located, err := s.resolve(projectID, target)
+		if err != nil {
+			return nil, err
+		}
+		value, revision, err := s.projectRepo.FindContent(projectID, path)
+		if err != nil {
+			return nil, err
+		}
+		// Si el documento cambió entre ubicar el nodo y leerlo, la ruta puede apuntar a otro
+		if located > 0 && revision != located && attempt < maxContentAttempts {
+			continue
+		}
+		return &dto.ContentResponse{
+			ProjectID: projectID,
+			Revision:  revision,
+			Pointer:   content.FormatPointer(path),
+			Value:     json.RawMessage(value),
+		}, nil
+	}
+}
+
+func (s *ContentServiceImpl) Set(projectID string, userID uuid.UUID, target dto.ContentTarget, value json.RawMessage, expectedRevision int) (*dto.ContentResponse, error) {
+	if err := s.authorize(projectID, userID, true); err != nil {
+		return nil, err
+	}
+
+	if target.NodeID != "" {
+		// Reemplazar un nodo conserva su id
+		node, err := parseNode(value)
+		if err != nil {
+			return nil, err
+		}
+		node[content.IDKey] = target.NodeID
+		if value, err = json.Marshal(node); err != nil {
+			return nil, err
+		}
+	}
+
+	project, path, err := s.mutate(projectID, target, expectedRevision, func(path []string) (repositories.ContentMutation, error) {
+		ids, err := writtenNodeIDs(path, value)
+		if err != nil {
+			return repositories.ContentMutation{}, err
+		}
+		return repositories.ContentMutation{Op: repositories.ContentSet, Path: path, Value: value, NodeIDs: ids}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pointer := content.FormatPointer(path)
+	s.publishUpdate(project, userID, pointer, repositories.ContentSet)
+	return &dto.ContentResponse{
+		ProjectID: projectID,
+		Revision:  project.Revision,
+		Pointer:   pointer,
+		Value:     value,
+	}, nil
+}
+
+func (s *ContentServiceImpl) Delete(projectID string, userID uuid.UUID, target dto.ContentTarget, expectedRevision int) (*dto.ContentResponse, error) {
+	if err := s.authorize(projectID, userID, true); err != nil {
+		return nil, err
+	}
+
+	project, path, err := s.mutate(projectID, target, expectedRevision, func(path []string) (repositories.ContentMutation, error) {
+		if len(path) == 0 {
+			return repositories.ContentMutation{}, services.ErrRootNotDeletable
+		}
+		if isNodeField(path, content.IDKey) {
+			// Un nodo sin id deja de ser un nodo
+			return repositories.ContentMutation{}, services.ErrInvalidNode
+		}
+		return repositories.ContentMutation{Op: repositories.ContentDelete, Path: path}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pointer := content.FormatPointer(path)
+	s.publishUpdate(project, userID, pointer, repositories.ContentDelete)
+	return &dto.ContentResponse{
+		ProjectID: projectID,
+		Revision:  project.Revision,
+		Pointer:   pointer,
+	}, nil
+}
+
+func (s *ContentServiceImpl) InsertChild(projectID string, userID uuid.UUID, target dto.ContentTarget, in *dto.InsertNodeInput, expectedRevision int) (*dto.ContentResponse, error) {
+	if err := s.authorize(projectID, userID, true); err != nil {
+		return nil, err
+	}
+
+	node, err := parseNode(in.Node)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := node[content.IDKey].(string)
+	ids, ok := content.SubtreeIDs(node)
+	if id == "" || !ok {
+		return nil, services.ErrInvalidNode
+	}
+
+	index := -1
+	if in.Index != nil {
+		index = *in.Index
+	}
+
+	project, _, err := s.mutate(projectID, target, expectedRevision, func(path []string) (repositories.ContentMutation, error) {
+		return repositories.ContentMutation{
+			Op:      repositories.ContentInsert,
+			Path:    path,
+			Value:   in.Node,
+			Index:   index,
+			NodeIDs: ids,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// La respuesta apunta al nodo insertado, no a su padre
+	var pointer string
+	if doc, err := content.Parse(project.Content); err == nil {
+		if path, ok := content.NodePointer(doc, id); ok {
+			pointer = content.FormatPointer(path)
+		}
+	}
+
+	s.publishUpdate(project, userID, pointer, repositories.ContentInsert)
+	return &dto.ContentResponse{
+		ProjectID: projectID,
+		Revision:  project.Revision,
+		Pointer:   pointer,
+		Value:     in.Node,
+	}, nil
+}
+
+// authorize verifica que el usuario pueda leer el proyecto o, si write, modificarlo
+func (s *ContentServiceImpl) authorize(projectID string, userID uuid.UUID, write bool) error {
+	role, err := s.projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" || (write && role == entity.ProjectRoleViewer) {
+		return services.ErrForbidden
+	}
+	return nil
+}
+
+// resolve convierte el destino en la ruta dentro del documento. Para un nodo también
+// retorna la revisión en la que se lo ubicó; para un JSON Pointer esa revisión es 0.
+func (s *ContentServiceImpl) resolve(projectID string, target dto.ContentTarget) ([]string, int, error) {
+	if target.NodeID == "" {
+		path, err := content.ParsePointer(target.Pointer)
+		return path, 0, err
+	}
+
+	project, err := s.projectRepo.FindByID(projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	doc, err := content.Parse(project.Content)
+	if err != nil {
+		return nil, 0, err
+	}
+	path, ok := content.NodePointer(doc, target.NodeID)
+	if !ok {
+		return nil, 0, repositories.ErrContentPathNotFound
+	}
+	return path, project.Revision, nil
+}
+
+// mutate aplica la mutación construida por build. Un nodo ubicado por ID exige que el documento
+// no haya cambiado desde que se lo buscó; si el cliente no fijó la revisión se vuelve a intentar.
+func (s *ContentServiceImpl) mutate(projectID string, target dto.ContentTarget, expectedRevision int, build func(path []string) (repositories.ContentMutation, error)) (*entity.Project, []string, error) {
+	for attempt := 1; ; attempt++ {
+		path, located, err := s.resolve(projectID, target)
+		if err != nil {
+			return nil, nil, err
+		}
+		mutation, err := build(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mutation.ExpectedRevision = expectedRevision
+		if expectedRevision == 0 {
+			mutation.ExpectedRevision = located
+		}
+
+		project, err := s.projectRepo.MutateContent(projectID, mutation)
+		if errors.Is(err, repositories.ErrRevisionConflict) && expectedRevision == 0 && attempt < maxContentAttempts {
+			continue
+		}
+		return project, path, err
+	}
+}
+
+func (s *ContentServiceImpl) publishUpdate(project *entity.Project, userID uuid.UUID, pointer, operation string) {
+	s.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: project.ID.String(),
+		UserID:    userID.String(),
+		Data: map[string]interface{}{
+			"title":     project.Title,
+			"revision":  project.Revision,
+			"changed":   []string{"content"},
+			"pointer":   pointer,
+			"operation": operation,
+		},
+	})
+}
+
+// writtenNodeIDs verifica que escribir value en path mantenga el árbol válido y retorna los
+// ids de los nodos que escribe. Reemplazar un nodo, su id o sus hijos exige nodos válidos.
+func writtenNodeIDs(path []string, value json.RawMessage) ([]string, error) {
+	switch {
+	case len(path) == 0:
+		doc, err := content.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := content.SubtreeIDs(doc); !ok {
+			return nil, services.ErrInvalidNode
+		}
+		// Reemplaza el documento completo: no hay otros nodos con los que repetir ids
+		return nil, nil
+
+	case content.IsNodePath(path):
+		node, err := parseNode(value)
+		if err != nil {
+			return nil, err
+		}
+		ids, ok := content.SubtreeIDs(node)
+		if id, _ := node[content.IDKey].(string); id == "" || !ok {
+			return nil, services.ErrInvalidNode
+		}
+		return ids, nil
+
+	case isNodeField(path, content.IDKey):
+		var id string
+		if err := json.Unmarshal(value, &id); err != nil || id == "" {
+			return nil, services.ErrInvalidNode
+		}
+		return []string{id}, nil
+
+	case isNodeField(path, content.ChildrenKey):
+		var children []interface{}
+		if err := json.Unmarshal(value, &children); err != nil || children == nil {
+			return nil, services.ErrInvalidNode
+		}
+		ids, ok := content.SubtreeIDs(map[string]interface{}{content.ChildrenKey: children})
+		if !ok {
+			return nil, services.ErrInvalidNode
+		}
+		return ids, nil
+	}
+	return nil, nil
+}
+
+// isNodeField indica si path apunta a la clave key de un nodo y no a una propiedad anidada
+func isNodeField(path []string, key string) bool {
+	last := len(path) - 1
+	return last >= 0 && path[last] == key && content.IsNodePath(path[:last])
+}
+
+// parseNode decodifica un nodo del árbol, que siempre es un objeto JSON
+func parseNode(raw json.RawMessage) (map[string]interface{}, error) {
+	var node map[string]interface{}
+	if err := json.Unmarshal(raw, &node); err != nil || node == nil {
+		return nil, services.ErrInvalidNode
+	}
+	return node, nil
+}
@@ -0,0 +1,46 @@
+package impl
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+)
+
+// Escribir un nodo, su id o sus hijos exige nodos válidos y retorna sus ids para verificar
+// que no se repitan; las demás propiedades se escriben sin verificar
+func TestWrittenNodeIDs(t *testing.T) {
+	tests := []struct {
+		pointer []string
+		value   string
+		ids     []string
+		invalid bool
+	}{
+		{nil, `{"id":"root","children":[{"id":"a"},{"id":"b"}]}`, nil, false},
+		{nil, `{"id":"root","children":[{"id":"a"},{"id":"a"}]}`, nil, true},
+		{[]string{"children", "0"}, `{"id":"a","children":[{"id":"b"}]}`, []string{"a", "b"}, false},
+		{[]string{"children", "0"}, `{"type":"Text"}`, nil, true},
+		{[]string{"children", "0"}, `"texto"`, nil, true},
+		{[]string{"children", "0", "id"}, `"nuevo"`, []string{"nuevo"}, false},
+		{[]string{"children", "0", "id"}, `3`, nil, true},
+		{[]string{"children", "0", "children"}, `[{"id":"x"},{"id":"y","children":[{"id":"z"}]}]`, []string{"x", "y", "z"}, false},
+		{[]string{"children", "0", "children"}, `[{"id":"x"},{"text":"sin id"}]`, nil, true},
+		{[]string{"children", "0", "children"}, `{}`, nil, true},
+		{[]string{"children", "0", "style", "id"}, `3`, nil, false},
+		{[]string{"children", "0", "text"}, `null`, nil, false},
+	}
+	for _, test := range tests {
+		ids, err := writtenNodeIDs(test.pointer, json.RawMessage(test.value))
+		if test.invalid {
+			if !errors.Is(err, services.ErrInvalidNode) {
+				t.Errorf("%v = %s: se esperaba ErrInvalidNode, se obtuvo %v", test.pointer, test.value, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%v = %s: se esperaba %v, se obtuvo %v (%v)", test.pointer, test.value, test.ids, ids, err)
+		}
+	}
+}
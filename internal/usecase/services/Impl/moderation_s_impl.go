@@ -0,0 +1,203 @@
+package impl
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxSanctionDuration      = 365 * 24 * time.Hour
+	defaultModerationLogSize = 50
+	maxModerationLogSize     = 200
+)
+
+type ModerationServiceImpl struct {
+	repo           repositories.ModerationRepository
+	projectService services.ProjectService
+	events         *event.Bus
+}
+
+func NewModerationService(repo repositories.ModerationRepository, projectService services.ProjectService, events *event.Bus) services.ModerationService {
+	return &ModerationServiceImpl{
+		repo:           repo,
+		projectService: projectService,
+		events:         events,
+	}
+}
+
+func (s *ModerationServiceImpl) Mute(projectID string, actorID, userID uuid.UUID, duration time.Duration, reason string) (*entity.RoomSanction, error) {
+	if duration < 0 || duration > maxSanctionDuration {
+		return nil, services.ErrInvalidDuration
+	}
+	return s.sanction(projectID, actorID, userID, entity.ModerationMute, duration, reason)
+}
+
+func (s *ModerationServiceImpl) Unmute(projectID string, actorID, userID uuid.UUID) error {
+	return s.lift(projectID, actorID, userID, entity.ModerationMute, entity.ModerationUnmute)
+}
+
+func (s *ModerationServiceImpl) Kick(projectID string, actorID, userID uuid.UUID, reason string) error {
+	if err := s.requireModerator(projectID, actorID, userID); err != nil {
+		return err
+	}
+	return s.record(projectID, actorID, userID, entity.ModerationKick, reason, nil)
+}
+
+func (s *ModerationServiceImpl) Ban(projectID string, actorID, userID uuid.UUID, duration time.Duration, reason string) (*entity.RoomSanction, error) {
+	if duration <= 0 || duration > maxSanctionDuration {
+		return nil, services.ErrInvalidDuration
+	}
+	return s.sanction(projectID, actorID, userID, entity.ModerationBan, duration, reason)
+}
+
+func (s *ModerationServiceImpl) Unban(projectID string, actorID, userID uuid.UUID) error {
+	return s.lift(projectID, actorID, userID, entity.ModerationBan, entity.ModerationUnban)
+}
+
+func (s *ModerationServiceImpl) ActiveSanction(projectID string, userID uuid.UUID, kind string) (*entity.RoomSanction, error) {
+	sanction, err := s.repo.FindActiveSanction(projectID, userID.String(), kind, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return sanction, err
+}
+
+func (s *ModerationServiceImpl) GetLog(projectID string, actorID uuid.UUID, limit int) ([]entity.ModerationLog, error) {
+	if err := s.requireManager(projectID, actorID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultModerationLogSize
+	}
+	if limit > maxModerationLogSize {
+		limit = maxModerationLogSize
+	}
+	return s.repo.FindLogs(projectID, limit)
+}
+
+// sanction reemplaza la sanción vigente del tipo dado por una nueva
+func (s *ModerationServiceImpl) sanction(projectID string, actorID, userID uuid.UUID, kind string, duration time.Duration, reason string) (*entity.RoomSanction, error) {
+	if err := s.requireModerator(projectID, actorID, userID); err != nil {
+		return nil, err
+	}
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	sanction := &entity.RoomSanction{
+		ProjectID: pid,
+		UserID:    userID,
+		Kind:      kind,
+		Reason:    reason,
+		CreatedBy: actorID,
+	}
+	if duration > 0 {
+		expiresAt := time.Now().Add(duration)
+		sanction.ExpiresAt = &expiresAt
+	}
+
+	if _, err := s.repo.LiftSanctions(projectID, userID.String(), kind); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSanction(sanction); err != nil {
+		return nil, err
+	}
+	if err := s.record(projectID, actorID, userID, kind, reason, sanction.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return sanction, nil
+}
+
+// lift levanta las sanciones del tipo dado; gorm.ErrRecordNotFound si el usuario no tenía
+func (s *ModerationServiceImpl) lift(projectID string, actorID, userID uuid.UUID, kind, action string) error {
+	if err := s.requireModerator(projectID, actorID, userID); err != nil {
+		return err
+	}
+	lifted, err := s.repo.LiftSanctions(projectID, userID.String(), kind)
+	if err != nil {
+		return err
+	}
+	if lifted == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return s.record(projectID, actorID, userID, action, "", nil)
+}
+
+// record guarda la acción en el registro de moderación y publica el evento
+func (s *ModerationServiceImpl) record(projectID string, actorID, userID uuid.UUID, action, reason string, expiresAt *time.Time) error {
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return err
+	}
+	entry := &entity.ModerationLog{
+		ProjectID: pid,
+		ActorID:   actorID,
+		TargetID:  userID,
+		Action:    action,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateLog(entry); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.RoomModeration,
+		ProjectID: projectID,
+		UserID:    actorID.String(),
+		Data: map[string]interface{}{
+			"action":     action,
+			"target_id":  userID.String(),
+			"reason":     reason,
+			"expires_at": expiresAt,
+		},
+	})
+	return nil
+}
+
+// requireModerator verifica que el actor pueda moderar al usuario: debe ser dueño o
+// administrador, no puede moderarse a sí mismo ni al dueño, y un administrador no puede
+// moderar a otro
+func (s *ModerationServiceImpl) requireModerator(projectID string, actorID, userID uuid.UUID) error {
+	actorRole, err := s.projectService.GetUserRole(projectID, actorID)
+	if err != nil {
+		return err
+	}
+	if actorRole != entity.ProjectRoleOwner && actorRole != entity.ProjectRoleAdmin {
+		return services.ErrForbidden
+	}
+	if actorID == userID {
+		return services.ErrInvalidTarget
+	}
+
+	targetRole, err := s.projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case targetRole == entity.ProjectRoleOwner:
+		return services.ErrInvalidTarget
+	case targetRole == entity.ProjectRoleAdmin && actorRole != entity.ProjectRoleOwner:
+		return services.ErrForbidden
+	}
+	return nil
+}
+
+func (s *ModerationServiceImpl) requireManager(projectID string, userID uuid.UUID) error {
+	role, err := s.projectService.GetUserRole(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != entity.ProjectRoleOwner && role != entity.ProjectRoleAdmin {
+		return services.ErrForbidden
+	}
+	return nil
+}
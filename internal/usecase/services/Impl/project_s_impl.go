@@ -1,21 +1,51 @@
 package impl
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
 	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type ProjectServiceImpl struct {
-	repo repositories.ProjectRepository
+	repo       repositories.ProjectRepository
+	memberRepo repositories.MemberRepository
+	events     *event.Bus
 }
 
-func NewProjectService(repo repositories.ProjectRepository) services.ProjectService {
-	return &ProjectServiceImpl{repo: repo}
+func NewProjectService(repo repositories.ProjectRepository, memberRepo repositories.MemberRepository, events *event.Bus) services.ProjectService {
+	return &ProjectServiceImpl{
+		repo:       repo,
+		memberRepo: memberRepo,
+		events:     events,
+	}
 }
 
 func (s *ProjectServiceImpl) CreateProject(project *entity.Project) error {
-	return s.repo.Create(project)
+	if err := s.repo.Create(project); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.ProjectCreated,
+		ProjectID: project.ID.String(),
+		UserID:    project.OwnerID.String(),
+		Data: map[string]interface{}{
+			"title":    project.Title,
+			"revision": project.Revision,
+		},
+	})
+	return nil
 }
 
 func (s *ProjectServiceImpl) GetProjectByID(id string) (*entity.Project, error) {
@@ -27,9 +57,351 @@ func (s *ProjectServiceImpl) GetAllProjects() ([]entity.Project, error) {
 }
 
 func (s *ProjectServiceImpl) UpdateProject(project *entity.Project) error {
-	return s.repo.Update(project)
+	current, err := s.repo.FindByID(project.ID.String())
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Update(project); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: project.ID.String(),
+		UserID:    project.OwnerID.String(),
+		Data: map[string]interface{}{
+			"title":    project.Title,
+			"revision": project.Revision,
+			"changed":  changedFields(current, project),
+		},
+	})
+	return nil
+}
+
+func (s *ProjectServiceImpl) SaveRoomContent(id string, content datatypes.JSON, revision int) (*entity.Project, error) {
+	// La revisión esperada evita pisar lo que se guardó por la API mientras la sala editaba
+	project, err := s.repo.MutateContent(id, repositories.ContentMutation{
+		Op:               repositories.ContentSet,
+		Value:            content,
+		ExpectedRevision: revision,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.ProjectUpdated,
+		ProjectID: id,
+		Data: map[string]interface{}{
+			"title":    project.Title,
+			"revision": project.Revision,
+			"changed":  []string{"content"},
+			"source":   "room",
+		},
+	})
+	return project, nil
+}
+
+// changedFields lista los campos editables que difieren entre dos estados del proyecto
+func changedFields(before, after *entity.Project) []string {
+	changed := []string{}
+	if before.Title != after.Title {
+		changed = append(changed, "title")
+	}
+	if before.Description != after.Description {
+		changed = append(changed, "description")
+	}
+	if !bytes.Equal(before.Content, after.Content) {
+		changed = append(changed, "content")
+	}
+	if before.MaxEditors != after.MaxEditors {
+		changed = append(changed, "max_editors")
+	}
+	if before.MaxSpectators != after.MaxSpectators {
+		changed = append(changed, "max_spectators")
+	}
+	return changed
 }
 
 func (s *ProjectServiceImpl) DeleteProject(id string) error {
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.ProjectDeleted,
+		ProjectID: id,
+	})
+	return nil
+}
+
+func (s *ProjectServiceImpl) GetProjectVersion(id string, revision int) (*entity.ProjectVersion, error) {
+	return s.repo.FindVersion(id, revision)
+}
+
+func (s *ProjectServiceImpl) GetProjectVersions(id string) ([]entity.ProjectVersion, error) {
+	return s.repo.FindVersions(id)
+}
+
+func (s *ProjectServiceImpl) DiffProjectVersions(id string, from, to int) (*dto.ProjectDiffResponse, error) {
+	if to <= 0 {
+		project, err := s.repo.FindByID(id)
+		if err != nil {
+			return nil, err
+		}
+		to = project.Revision
+	}
+	if from <= 0 {
+		from = to - 1
+		if from < 1 {
+			from = 1
+		}
+	}
+
+	fromVersion, err := s.repo.FindVersion(id, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.repo.FindVersion(id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := content.DiffDocuments(fromVersion.Content, toVersion.Content)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ProjectDiffResponse{
+		ProjectID:    id,
+		FromRevision: from,
+		ToRevision:   to,
+		Diff:         diff,
+	}, nil
+}
+
+func (s *ProjectServiceImpl) CreateBranch(projectID string, userID uuid.UUID, name string) (*entity.Project, error) {
+	role, err := s.GetUserRole(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, services.ErrForbidden
+	}
+
+	parent, err := s.repo.FindByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := &entity.Project{
+		Title:        fmt.Sprintf("%s (%s)", parent.Title, name),
+		Description:  parent.Description,
+		Content:      parent.Content,
+		OwnerID:      userID,
+		ParentID:     &parent.ID,
+		BranchName:   name,
+		BaseRevision: parent.Revision,
+	}
+	if err := s.CreateProject(branch); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+func (s *ProjectServiceImpl) GetBranches(projectID string) ([]entity.Project, error) {
+	return s.repo.FindByParent(projectID)
+}
+
+func (s *ProjectServiceImpl) MergeBranch(projectID string, userID uuid.UUID, input *dto.MergeBranchInput) (*dto.MergeBranchResponse, error) {
+	for _, resolution := range input.Resolutions {
+		if resolution != content.ResolveOurs && resolution != content.ResolveTheirs {
+			return nil, services.ErrInvalidResolution
+		}
+	}
+
+	role, err := s.GetUserRole(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" || role == entity.ProjectRoleViewer {
+		return nil, services.ErrForbidden
+	}
+
+	target, err := s.repo.FindByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	branch, err := s.repo.FindByID(input.BranchID)
+	if err != nil {
+		return nil, err
+	}
+	if branch.ParentID == nil || *branch.ParentID != target.ID {
+		return nil, services.ErrNotABranch
+	}
+
+	baseVersion, err := s.mergeBase(branch)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := content.Parse(baseVersion.Content)
+	if err != nil {
+		return nil, err
+	}
+	ours, err := content.Parse(target.Content)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := content.Parse(branch.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	result := content.Merge(base, ours, theirs, input.Resolutions)
+	if len(result.Conflicts) > 0 {
+		return &dto.MergeBranchResponse{Conflicts: result.Conflicts}, nil
+	}
+
+	merged, err := json.Marshal(result.Document)
+	if err != nil {
+		return nil, err
+	}
+	target.Content = datatypes.JSON(merged)
+	if err := s.UpdateProject(target); err != nil {
+		return nil, err
+	}
+
+	// Las próximas integraciones parten del contenido de la rama que se acaba de integrar: el
+	// proyecto lo contiene y la rama sigue desde ahí
+	if err := s.repo.UpdateMergeBase(branch.ID.String(), target.Revision, branch.Revision); err != nil {
+		return nil, err
+	}
+
+	return &dto.MergeBranchResponse{
+		Merged:    true,
+		Project:   target,
+		Conflicts: result.Conflicts,
+	}, nil
+}
+
+// mergeBase retorna el ancestro común de la rama y su padre: la revisión de la rama integrada
+// por última vez, o la del padre desde la que se bifurcó si nunca se integró
+func (s *ProjectServiceImpl) mergeBase(branch *entity.Project) (*entity.ProjectVersion, error) {
+	if branch.MergedRevision > 0 {
+		return s.repo.FindVersion(branch.ID.String(), branch.MergedRevision)
+	}
+	return s.repo.FindVersion(branch.ParentID.String(), branch.BaseRevision)
+}
+
+func (s *ProjectServiceImpl) GetUserRole(projectID string, userID uuid.UUID) (string, error) {
+	project, err := s.repo.FindByID(projectID)
+	if err != nil {
+		return "", err
+	}
+	if project.OwnerID == userID {
+		return entity.ProjectRoleOwner, nil
+	}
+
+	member, err := s.memberRepo.Find(projectID, userID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Sin membresía no hay acceso
+			return "", nil
+		}
+		return "", err
+	}
+	return member.Role, nil
+}
+
+func (s *ProjectServiceImpl) GetAccessibleProjectIDs(userID uuid.UUID) ([]string, error) {
+	owned, err := s.repo.FindIDsByOwner(userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := s.memberRepo.FindProjectIDsByUser(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	return append(owned, shared...), nil
+}
+
+func (s *ProjectServiceImpl) GetMembers(projectID string) ([]entity.ProjectMember, error) {
+	return s.memberRepo.FindByProject(projectID)
+}
+
+func (s *ProjectServiceImpl) AddMember(projectID string, actorID, userID uuid.UUID, role string) (*entity.ProjectMember, error) {
+	if role == "" {
+		role = entity.ProjectRoleEditor
+	}
+	if role != entity.ProjectRoleAdmin && role != entity.ProjectRoleEditor && role != entity.ProjectRoleViewer {
+		return nil, services.ErrInvalidRole
+	}
+
+	if err := s.requireManager(projectID, actorID); err != nil {
+		return nil, err
+	}
+
+	project, err := s.repo.FindByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.OwnerID == userID {
+		return nil, services.ErrInvalidRole
+	}
+
+	member := &entity.ProjectMember{
+		ProjectID: project.ID,
+		UserID:    userID,
+		Role:      role,
+	}
+	if err := s.memberRepo.Save(member); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.MemberAdded,
+		ProjectID: projectID,
+		UserID:    actorID.String(),
+		Data: map[string]interface{}{
+			"member_id": userID.String(),
+			"role":      role,
+		},
+	})
+	return member, nil
+}
+
+func (s *ProjectServiceImpl) RemoveMember(projectID string, actorID, userID uuid.UUID) error {
+	// Un miembro siempre puede salir del proyecto por su cuenta
+	if actorID != userID {
+		if err := s.requireManager(projectID, actorID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.memberRepo.Delete(projectID, userID.String()); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.MemberRemoved,
+		ProjectID: projectID,
+		UserID:    actorID.String(),
+		Data: map[string]interface{}{
+			"member_id": userID.String(),
+		},
+	})
+	return nil
+}
+
+// requireManager verifica que el usuario sea dueño o administrador del proyecto
+func (s *ProjectServiceImpl) requireManager(projectID string, userID uuid.UUID) error {
+	role, err := s.GetUserRole(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != entity.ProjectRoleOwner && role != entity.ProjectRoleAdmin {
+		return services.ErrForbidden
+	}
+	return nil
 }
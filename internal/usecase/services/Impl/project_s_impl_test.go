@@ -0,0 +1,190 @@
+package impl
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/content"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// memoryProjects es un ProjectRepository en memoria con las revisiones y versiones que usan
+// las ramas
+type memoryProjects struct {
+	repositories.ProjectRepository
+	mutex    sync.Mutex
+	projects map[uuid.UUID]entity.Project
+	versions map[uuid.UUID][]entity.ProjectVersion
+}
+
+func newMemoryProjects() *memoryProjects {
+	return &memoryProjects{
+		projects: make(map[uuid.UUID]entity.Project),
+		versions: make(map[uuid.UUID][]entity.ProjectVersion),
+	}
+}
+
+func (r *memoryProjects) Create(project *entity.Project) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	project.ID = uuid.New()
+	project.Revision = 1
+	r.save(project)
+	return nil
+}
+
+func (r *memoryProjects) FindByID(id string) (*entity.Project, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	project, ok := r.projects[uuid.MustParse(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &project, nil
+}
+
+func (r *memoryProjects) Update(project *entity.Project) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	current := r.projects[project.ID]
+	project.Revision = current.Revision + 1
+	project.ParentID = current.ParentID
+	project.BaseRevision = current.BaseRevision
+	project.MergedRevision = current.MergedRevision
+	r.save(project)
+	return nil
+}
+
+func (r *memoryProjects) UpdateMergeBase(id string, baseRevision, mergedRevision int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	project := r.projects[uuid.MustParse(id)]
+	project.BaseRevision = baseRevision
+	project.MergedRevision = mergedRevision
+	r.projects[project.ID] = project
+	return nil
+}
+
+func (r *memoryProjects) FindVersion(projectID string, revision int) (*entity.ProjectVersion, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, version := range r.versions[uuid.MustParse(projectID)] {
+		if version.Revision == revision {
+			return &version, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryProjects) save(project *entity.Project) {
+	r.projects[project.ID] = *project
+	r.versions[project.ID] = append(r.versions[project.ID], entity.ProjectVersion{
+		ProjectID:   project.ID,
+		Revision:    project.Revision,
+		Title:       project.Title,
+		Description: project.Description,
+		Content:     project.Content,
+	})
+}
+
+// editContent aplica una operación al Content guardado del proyecto, como un guardado de la sala
+func editContent(t *testing.T, s *ProjectServiceImpl, projectID uuid.UUID, op content.Operation) {
+	t.Helper()
+	project, err := s.GetProjectByID(projectID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := content.Parse(project.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := content.Apply(doc, op); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(doc)
+	project.Content = datatypes.JSON(data)
+	if err := s.UpdateProject(project); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func insertText(id string) content.Operation {
+	return content.Operation{
+		Type:     content.OpInsertNode,
+		NodeID:   id,
+		ParentID: content.RootID,
+		Index:    -1,
+		Node:     map[string]interface{}{content.TypeKey: "Text"},
+	}
+}
+
+func mergeBranch(t *testing.T, s *ProjectServiceImpl, parentID, branchID, ownerID uuid.UUID) map[string]interface{} {
+	t.Helper()
+	result, err := s.MergeBranch(parentID.String(), ownerID, &dto.MergeBranchInput{BranchID: branchID.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Merged {
+		t.Fatalf("la integración tuvo conflictos: %+v", result.Conflicts)
+	}
+	doc, _ := content.Parse(result.Project.Content)
+	return doc
+}
+
+func sortedChildren(doc map[string]interface{}) []string {
+	var ids []string
+	for _, child := range doc[content.ChildrenKey].([]interface{}) {
+		ids = append(ids, child.(map[string]interface{})[content.IDKey].(string))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Integrar dos veces la misma rama conserva lo que el proyecto agregó o cambió por su cuenta
+func TestMergeSameBranchTwice(t *testing.T) {
+	owner := uuid.New()
+	service := NewProjectService(newMemoryProjects(), nil, event.NewBus()).(*ProjectServiceImpl)
+	parent := &entity.Project{
+		Title:   "App",
+		OwnerID: owner,
+		Content: datatypes.JSON(`{"id":"root","children":[{"id":"a","type":"Text","text":"hola"}]}`),
+	}
+	if err := service.CreateProject(parent); err != nil {
+		t.Fatal(err)
+	}
+	branch, err := service.CreateBranch(parent.ID.String(), owner, "rediseño")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	editContent(t, service, branch.ID, insertText("x"))
+	editContent(t, service, parent.ID, insertText("p"))
+	merged := mergeBranch(t, service, parent.ID, branch.ID, owner)
+	if got := sortedChildren(merged); !reflect.DeepEqual(got, []string{"a", "p", "x"}) {
+		t.Fatalf("primera integración: se esperaba [a p x], se obtuvo %v", got)
+	}
+
+	editContent(t, service, parent.ID, content.Operation{Type: content.OpSetProp, NodeID: "a", Key: "text", Value: "chau"})
+	editContent(t, service, branch.ID, insertText("y"))
+	merged = mergeBranch(t, service, parent.ID, branch.ID, owner)
+	if got := sortedChildren(merged); !reflect.DeepEqual(got, []string{"a", "p", "x", "y"}) {
+		t.Fatalf("segunda integración: se esperaba [a p x y], se obtuvo %v", got)
+	}
+	if text := merged[content.ChildrenKey].([]interface{})[0].(map[string]interface{})["text"]; text != "chau" {
+		t.Fatalf("la segunda integración revirtió el cambio del proyecto: text = %v", text)
+	}
+
+	// Sin cambios nuevos en la rama integrar otra vez no cambia nada
+	if again := mergeBranch(t, service, parent.ID, branch.ID, owner); !reflect.DeepEqual(again, merged) {
+		t.Fatalf("la tercera integración cambió el proyecto:\nantes   %v\ndespués %v", merged, again)
+	}
+}
@@ -0,0 +1,228 @@
+package impl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const maxSlugBaseLength = 48
+
+type PublicationServiceImpl struct {
+	repo        repositories.PublicationRepository
+	projectRepo repositories.ProjectRepository
+	events      *event.Bus
+}
+
+func NewPublicationService(repo repositories.PublicationRepository, projectRepo repositories.ProjectRepository, events *event.Bus) services.PublicationService {
+	return &PublicationServiceImpl{
+		repo:        repo,
+		projectRepo: projectRepo,
+		events:      events,
+	}
+}
+
+func (s *PublicationServiceImpl) Publish(projectID string, userID uuid.UUID, revision int) (*entity.ProjectPublication, error) {
+	project, err := s.ownedProject(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision <= 0 {
+		revision = project.Revision
+	}
+	version, err := s.projectRepo.FindVersion(projectID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := sanitizeContent(version.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	publication, err := s.repo.FindByProjectID(projectID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// Primera publicación: el slug se genera una sola vez y se reutiliza al republicar
+		slug, err := s.newSlug(project.Title)
+		if err != nil {
+			return nil, err
+		}
+		publication = &entity.ProjectPublication{
+			ProjectID: project.ID,
+			Slug:      slug,
+		}
+	}
+
+	now := time.Now()
+	publication.Revision = version.Revision
+	publication.Title = version.Title
+	publication.Description = version.Description
+	publication.Content = content
+	publication.Published = true
+	publication.PublishedAt = &now
+	publication.UnpublishedAt = nil
+
+	if err := s.repo.Save(publication); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(event.Event{
+		Type:      event.VersionPublished,
+		ProjectID: projectID,
+		UserID:    userID.String(),
+		Data: map[string]interface{}{
+			"slug":     publication.Slug,
+			"revision": publication.Revision,
+		},
+	})
+	return publication, nil
+}
+
+func (s *PublicationServiceImpl) Unpublish(projectID string, userID uuid.UUID) (*entity.ProjectPublication, error) {
+	if _, err := s.ownedProject(projectID, userID); err != nil {
+		return nil, err
+	}
+
+	publication, err := s.repo.FindByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if publication.Published {
+		now := time.Now()
+		publication.Published = false
+		publication.UnpublishedAt = &now
+		if err := s.repo.Save(publication); err != nil {
+			return nil, err
+		}
+	}
+	return publication, nil
+}
+
+func (s *PublicationServiceImpl) GetPublication(projectID string, userID uuid.UUID) (*entity.ProjectPublication, error) {
+	if _, err := s.ownedProject(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByProjectID(projectID)
+}
+
+func (s *PublicationServiceImpl) GetPublishedBySlug(slug string) (*entity.ProjectPublication, error) {
+	publication, err := s.repo.FindBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if !publication.Published {
+		return nil, services.ErrProjectNotPublished
+	}
+	return publication, nil
+}
+
+// ownedProject obtiene el proyecto y verifica que el usuario sea su dueño
+func (s *PublicationServiceImpl) ownedProject(projectID string, userID uuid.UUID) (*entity.Project, error) {
+	project, err := s.projectRepo.FindByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.OwnerID != userID {
+		return nil, services.ErrForbidden
+	}
+	return project, nil
+}
+
+func (s *PublicationServiceImpl) newSlug(title string) (string, error) {
+	base := slugify(title)
+	for attempt := 0; attempt < 5; attempt++ {
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		slug := hex.EncodeToString(suffix)
+		if base != "" {
+			slug = base + "-" + slug
+		}
+
+		exists, err := s.repo.SlugExists(slug)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+	}
+	return "", errors.New("could not generate a unique slug")
+}
+
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= maxSlugBaseLength {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// sanitizeContent elimina recursivamente las claves privadas del editor (prefijo "_")
+// para que no se expongan en la página pública
+func sanitizeContent(raw datatypes.JSON) (datatypes.JSON, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	clean, err := json.Marshal(stripPrivateKeys(doc))
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(clean), nil
+}
+
+func stripPrivateKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if strings.HasPrefix(key, "_") {
+				delete(v, key)
+				continue
+			}
+			v[key] = stripPrivateKeys(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = stripPrivateKeys(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
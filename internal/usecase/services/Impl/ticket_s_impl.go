@@ -0,0 +1,66 @@
+package impl
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TicketServiceImpl struct {
+	repo repositories.TicketRepository
+}
+
+func NewTicketService(repo repositories.TicketRepository) services.TicketService {
+	return &TicketServiceImpl{repo: repo}
+}
+
+func (s *TicketServiceImpl) Issue(userID uuid.UUID, ttl time.Duration) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	value := hex.EncodeToString(buf)
+	now := time.Now()
+
+	// Limpiar los tickets vencidos que nunca se usaron
+	if err := s.repo.DeleteExpired(now); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := &entity.ConnectionTicket{
+		TokenHash: ticketHash(value),
+		UserID:    userID,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.repo.Create(ticket); err != nil {
+		return "", time.Time{}, err
+	}
+	return value, ticket.ExpiresAt, nil
+}
+
+func (s *TicketServiceImpl) Redeem(value string) (uuid.UUID, error) {
+	ticket, err := s.repo.Consume(ticketHash(value))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, services.ErrInvalidTicket
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if time.Now().After(ticket.ExpiresAt) {
+		return uuid.Nil, services.ErrInvalidTicket
+	}
+	return ticket.UserID, nil
+}
+
+// ticketHash es la clave con la que se guarda el ticket; quien lea la tabla no puede usarlos
+func ticketHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
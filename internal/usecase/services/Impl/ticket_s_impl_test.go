@@ -0,0 +1,79 @@
+package impl
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryTickets es un TicketRepository en memoria, compartido como la tabla entre instancias
+type memoryTickets struct {
+	mutex   sync.Mutex
+	tickets map[string]entity.ConnectionTicket
+}
+
+func (r *memoryTickets) Create(ticket *entity.ConnectionTicket) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tickets[ticket.TokenHash] = *ticket
+	return nil
+}
+
+func (r *memoryTickets) Consume(tokenHash string) (*entity.ConnectionTicket, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ticket, ok := r.tickets[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	delete(r.tickets, tokenHash)
+	return &ticket, nil
+}
+
+func (r *memoryTickets) DeleteExpired(now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for hash, ticket := range r.tickets {
+		if !ticket.ExpiresAt.After(now) {
+			delete(r.tickets, hash)
+		}
+	}
+	return nil
+}
+
+// Un ticket emitido en una instancia se canjea en otra una sola vez, y no después de vencer
+func TestTicketRedeemedOnAnotherInstance(t *testing.T) {
+	repo := &memoryTickets{tickets: make(map[string]entity.ConnectionTicket)}
+	issuer, redeemer := NewTicketService(repo), NewTicketService(repo)
+	user := uuid.New()
+
+	value, _, err := issuer.Issue(user, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hash := range repo.tickets {
+		if hash == value {
+			t.Fatal("el ticket se guardó sin hash")
+		}
+	}
+	if got, err := redeemer.Redeem(value); err != nil || got != user {
+		t.Fatalf("se esperaba el usuario %s, se obtuvo %s (%v)", user, got, err)
+	}
+	if _, err := redeemer.Redeem(value); !errors.Is(err, services.ErrInvalidTicket) {
+		t.Fatalf("el segundo canje debía fallar, se obtuvo %v", err)
+	}
+
+	expired, _, err := issuer.Issue(user, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := redeemer.Redeem(expired); !errors.Is(err, services.ErrInvalidTicket) {
+		t.Fatalf("un ticket vencido debía fallar, se obtuvo %v", err)
+	}
+}
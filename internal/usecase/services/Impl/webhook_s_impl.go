@@ -0,0 +1,471 @@
+package impl
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/services"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	webhookMaxAttempts     = 6
+	webhookBaseBackoff     = 2 * time.Second
+	webhookMaxBackoff      = 10 * time.Minute
+	webhookTimeout         = 10 * time.Second
+	webhookWorkers         = 4
+	webhookQueueSize       = 256
+	webhookSweepInterval   = time.Minute
+	webhookDeliveriesLimit = 100
+	webhookMaxStoredBody   = 1024
+)
+
+var errWebhookAddressBlocked = errors.New("webhook address is not public")
+
+// webhookBlockedPrefixes son rangos que no son de internet y que IsGlobalUnicast no descarta
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+type WebhookServiceImpl struct {
+	repo        repositories.WebhookRepository
+	projectRepo repositories.ProjectRepository
+	client      *http.Client
+
+	queue    chan uuid.UUID
+	inflight map[uuid.UUID]bool
+	mutex    sync.Mutex
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewWebhookService crea el servicio de webhooks. Si client es nil se usa newWebhookClient.
+func NewWebhookService(repo repositories.WebhookRepository, projectRepo repositories.ProjectRepository, client *http.Client) services.WebhookService {
+	if client == nil {
+		client = newWebhookClient()
+	}
+	return &WebhookServiceImpl{
+		repo:        repo,
+		projectRepo: projectRepo,
+		client:      client,
+		queue:       make(chan uuid.UUID, webhookQueueSize),
+		inflight:    make(map[uuid.UUID]bool),
+		done:        make(chan struct{}),
+	}
+}
+
+// newWebhookClient crea el cliente de entregas: solo conecta a direcciones públicas, revisadas
+// después de resolver el DNS, y no sigue redirecciones
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: publicAddressOnly}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        webhookWorkers,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// Una redirección cuenta como respuesta que no es 2xx
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddressOnly rechaza la conexión si la dirección resuelta es privada, de loopback o link-local
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errWebhookAddressBlocked, addrPort.Addr())
+	}
+	return nil
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *WebhookServiceImpl) CreateWebhook(projectID string, userID uuid.UUID, input *dto.CreateWebhookInput) (*dto.CreateWebhookResponse, error) {
+	project, err := s.ownedProject(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range input.Events {
+		if e != "*" && !isKnownEvent(e) {
+			return nil, fmt.Errorf("%w: %s", services.ErrUnknownEvent, e)
+		}
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &entity.Webhook{
+		ProjectID: project.ID,
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    datatypes.NewJSONSlice(input.Events),
+		Active:    true,
+		CreatedBy: userID,
+	}
+	if err := s.repo.Create(webhook); err != nil {
+		return nil, err
+	}
+
+	return &dto.CreateWebhookResponse{Webhook: *webhook, Secret: secret}, nil
+}
+
+func (s *WebhookServiceImpl) GetWebhooks(projectID string, userID uuid.UUID) ([]entity.Webhook, error) {
+	if _, err := s.ownedProject(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByProject(projectID)
+}
+
+func (s *WebhookServiceImpl) DeleteWebhook(projectID, webhookID string, userID uuid.UUID) error {
+	if _, err := s.projectWebhook(projectID, webhookID, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(webhookID)
+}
+
+func (s *WebhookServiceImpl) GetDeliveries(projectID, webhookID string, userID uuid.UUID) ([]entity.WebhookDelivery, error) {
+	if _, err := s.projectWebhook(projectID, webhookID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDeliveries(webhookID, webhookDeliveriesLimit)
+}
+
+// Redeliver crea una nueva entrega con el mismo payload; la original queda intacta en el log
+func (s *WebhookServiceImpl) Redeliver(projectID, webhookID, deliveryID string, userID uuid.UUID) (*entity.WebhookDelivery, error) {
+	webhook, err := s.projectWebhook(projectID, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.repo.FindDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.WebhookID != webhook.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	delivery := &entity.WebhookDelivery{
+		WebhookID:    webhook.ID,
+		EventID:      original.EventID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		Status:       entity.WebhookDeliveryPending,
+		RedeliveryOf: &original.ID,
+	}
+	if err := s.repo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	s.enqueue(delivery.ID)
+	return delivery, nil
+}
+
+func (s *WebhookServiceImpl) Dispatch(e event.Event) {
+	// Las consultas a la base de datos no deben bloquear a quien publica el evento
+	go s.dispatch(e)
+}
+
+func (s *WebhookServiceImpl) dispatch(e event.Event) {
+	webhooks, err := s.repo.FindActiveByProject(e.ProjectID)
+	if err != nil {
+		log.Printf("Error loading webhooks for project %s: %v", e.ProjectID, err)
+		return
+	}
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(e.Type) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				log.Printf("Error marshaling event %s: %v", e.ID, err)
+				return
+			}
+		}
+
+		delivery := &entity.WebhookDelivery{
+			WebhookID: webhook.ID,
+			EventID:   e.ID,
+			Event:     e.Type,
+			Payload:   datatypes.JSON(payload),
+			Status:    entity.WebhookDeliveryPending,
+		}
+		if err := s.repo.CreateDelivery(delivery); err != nil {
+			log.Printf("Error creating delivery for webhook %s: %v", webhook.ID, err)
+			continue
+		}
+		s.enqueue(delivery.ID)
+	}
+}
+
+// Start inicia los workers de entrega y el barrido de reintentos pendientes
+func (s *WebhookServiceImpl) Start() {
+	for i := 0; i < webhookWorkers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+
+	s.wg.Add(1)
+	go s.sweeper()
+}
+
+func (s *WebhookServiceImpl) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+func (s *WebhookServiceImpl) worker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case id := <-s.queue:
+			s.deliver(id)
+			s.mutex.Lock()
+			delete(s.inflight, id)
+			s.mutex.Unlock()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// sweeper reencola las entregas vencidas, por ejemplo tras un reinicio o con la cola llena
+func (s *WebhookServiceImpl) sweeper() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(webhookSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deliveries, err := s.repo.FindDueDeliveries(time.Now(), webhookQueueSize)
+			if err != nil {
+				log.Printf("Error loading due webhook deliveries: %v", err)
+				continue
+			}
+			for _, delivery := range deliveries {
+				s.enqueue(delivery.ID)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *WebhookServiceImpl) enqueue(id uuid.UUID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	if s.inflight[id] {
+		return
+	}
+
+	select {
+	case s.queue <- id:
+		s.inflight[id] = true
+	default:
+		// Cola llena: el sweeper la tomará en el próximo barrido
+	}
+}
+
+func (s *WebhookServiceImpl) deliver(id uuid.UUID) {
+	delivery, err := s.repo.FindDelivery(id.String())
+	if err != nil {
+		log.Printf("Error loading webhook delivery %s: %v", id, err)
+		return
+	}
+	if delivery.Status == entity.WebhookDeliverySucceeded || delivery.Status == entity.WebhookDeliveryFailed {
+		return
+	}
+
+	webhook, err := s.repo.FindByID(delivery.WebhookID.String())
+	if err != nil || !webhook.Active {
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.Error = "webhook removed or inactive"
+		delivery.NextAttemptAt = nil
+		s.saveDelivery(delivery)
+		return
+	}
+
+	delivery.Attempts++
+	status, body, err := s.send(webhook, delivery)
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+
+	now := time.Now()
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = entity.WebhookDeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts || errors.Is(err, errWebhookAddressBlocked):
+		// Reintentar una dirección bloqueada no cambia el resultado
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.Error = deliveryError(status, err)
+		delivery.NextAttemptAt = nil
+	default:
+		delay := webhookBackoff(delivery.Attempts)
+		next := now.Add(delay)
+		delivery.Status = entity.WebhookDeliveryRetrying
+		delivery.Error = deliveryError(status, err)
+		delivery.NextAttemptAt = &next
+		time.AfterFunc(delay, func() { s.enqueue(id) })
+	}
+
+	s.saveDelivery(delivery)
+}
+
+// send hace el POST firmado. La firma es HMAC-SHA256 de "<timestamp>.<body>" con el secreto del webhook.
+func (s *WebhookServiceImpl) send(webhook *entity.Webhook, delivery *entity.WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-flutter-parcial2-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxStoredBody))
+	return resp.StatusCode, string(respBody), nil
+}
+
+func (s *WebhookServiceImpl) saveDelivery(delivery *entity.WebhookDelivery) {
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		log.Printf("Error saving webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func (s *WebhookServiceImpl) ownedProject(projectID string, userID uuid.UUID) (*entity.Project, error) {
+	project, err := s.projectRepo.FindByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.OwnerID != userID {
+		return nil, services.ErrForbidden
+	}
+	return project, nil
+}
+
+// projectWebhook verifica la propiedad del proyecto y que el webhook pertenezca a él
+func (s *WebhookServiceImpl) projectWebhook(projectID, webhookID string, userID uuid.UUID) (*entity.Webhook, error) {
+	project, err := s.ownedProject(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.repo.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.ProjectID != project.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return webhook, nil
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff duplica la espera en cada intento: 2s, 4s, 8s... hasta webhookMaxBackoff
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseBackoff << (attempt - 1)
+	if delay <= 0 || delay > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return delay
+}
+
+func deliveryError(status int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("unexpected status %d", status)
+}
+
+func isKnownEvent(eventType string) bool {
+	for _, t := range event.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package impl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/usecase/repositories"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// memoryWebhooks es un WebhookRepository en memoria con lo que usan las entregas
+type memoryWebhooks struct {
+	repositories.WebhookRepository
+	mutex      sync.Mutex
+	webhooks   map[uuid.UUID]entity.Webhook
+	deliveries map[uuid.UUID]entity.WebhookDelivery
+}
+
+func newMemoryWebhooks(webhooks ...entity.Webhook) *memoryWebhooks {
+	r := &memoryWebhooks{
+		webhooks:   make(map[uuid.UUID]entity.Webhook),
+		deliveries: make(map[uuid.UUID]entity.WebhookDelivery),
+	}
+	for _, webhook := range webhooks {
+		r.webhooks[webhook.ID] = webhook
+	}
+	return r
+}
+
+func (r *memoryWebhooks) FindByID(id string) (*entity.Webhook, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	webhook, ok := r.webhooks[uuid.MustParse(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &webhook, nil
+}
+
+func (r *memoryWebhooks) CreateDelivery(delivery *entity.WebhookDelivery) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delivery.ID = uuid.New()
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryWebhooks) UpdateDelivery(delivery *entity.WebhookDelivery) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryWebhooks) FindDelivery(id string) (*entity.WebhookDelivery, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delivery, ok := r.deliveries[uuid.MustParse(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &delivery, nil
+}
+
+// stubProjectRepo solo responde FindByID, para la verificación de propiedad
+type stubProjectRepo struct {
+	repositories.ProjectRepository
+	project entity.Project
+}
+
+func (r *stubProjectRepo) FindByID(id string) (*entity.Project, error) {
+	project := r.project
+	return &project, nil
+}
+
+// receivedRequest es un POST recibido por el receptor de prueba
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver levanta un receptor que responde con los códigos de statuses en orden; el último
+// se repite
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, chan receivedRequest) {
+	t.Helper()
+	received := make(chan receivedRequest, 16)
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedRequest{header: r.Header.Clone(), body: body}
+
+		mutex.Lock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		mutex.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte("respuesta"))
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func testWebhook(url string) entity.Webhook {
+	return entity.Webhook{ID: uuid.New(), ProjectID: uuid.New(), URL: url, Secret: "secreto", Active: true}
+}
+
+func pendingDelivery(t *testing.T, repo *memoryWebhooks, webhook entity.Webhook) uuid.UUID {
+	t.Helper()
+	delivery := &entity.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   "evento",
+		Event:     "project.updated",
+		Payload:   datatypes.JSON(`{"type":"project.updated"}`),
+		Status:    entity.WebhookDeliveryPending,
+	}
+	if err := repo.CreateDelivery(delivery); err != nil {
+		t.Fatal(err)
+	}
+	return delivery.ID
+}
+
+func nextRequest(t *testing.T, received chan receivedRequest) receivedRequest {
+	t.Helper()
+	select {
+	case request := <-received:
+		return request
+	case <-time.After(5 * time.Second):
+		t.Fatal("el receptor no recibió la entrega")
+		return receivedRequest{}
+	}
+}
+
+// La entrega lleva la firma HMAC del cuerpo, un error se reintenta con backoff y el siguiente
+// intento exitoso la completa
+func TestDeliverySignedAndRetried(t *testing.T) {
+	server, received := newReceiver(t, http.StatusInternalServerError, http.StatusNoContent)
+	webhook := testWebhook(server.URL)
+	repo := newMemoryWebhooks(webhook)
+	service := NewWebhookService(repo, &stubProjectRepo{}, server.Client()).(*WebhookServiceImpl)
+	defer service.Stop()
+	id := pendingDelivery(t, repo, webhook)
+
+	before := time.Now()
+	service.deliver(id)
+	after := time.Now()
+
+	request := nextRequest(t, received)
+	timestamp := request.header.Get("X-Webhook-Timestamp")
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(timestamp + "." + string(request.body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); request.header.Get("X-Webhook-Signature") != want {
+		t.Fatalf("firma %q, se esperaba %q", request.header.Get("X-Webhook-Signature"), want)
+	}
+	if request.header.Get("X-Webhook-Delivery") != id.String() || request.header.Get("X-Webhook-Event") != "project.updated" {
+		t.Fatalf("cabeceras inesperadas: %v", request.header)
+	}
+
+	delivery, _ := repo.FindDelivery(id.String())
+	if delivery.Status != entity.WebhookDeliveryRetrying || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("se esperaba un reintento tras el 500, se obtuvo %+v", delivery)
+	}
+	if next := delivery.NextAttemptAt; next == nil || next.Before(before.Add(webhookBaseBackoff)) || next.After(after.Add(webhookBaseBackoff)) {
+		t.Fatalf("el reintento debía programarse en %v, se programó para %v", webhookBaseBackoff, delivery.NextAttemptAt)
+	}
+
+	service.deliver(id)
+	nextRequest(t, received)
+	delivery, _ = repo.FindDelivery(id.String())
+	if delivery.Status != entity.WebhookDeliverySucceeded || delivery.Attempts != 2 || delivery.DeliveredAt == nil || delivery.NextAttemptAt != nil {
+		t.Fatalf("se esperaba la entrega completada en el segundo intento, se obtuvo %+v", delivery)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1:  2 * time.Second,
+		2:  4 * time.Second,
+		5:  32 * time.Second,
+		10: webhookMaxBackoff,
+		70: webhookMaxBackoff, // El corrimiento desborda
+	} {
+		if got := webhookBackoff(attempt); got != want {
+			t.Errorf("intento %d: se esperaba %v, se obtuvo %v", attempt, want, got)
+		}
+	}
+}
+
+// Reenviar crea otra entrega con el mismo payload y la envía; la original queda intacta
+func TestRedeliverSendsNewDelivery(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	webhook := testWebhook(server.URL)
+	owner := uuid.New()
+	repo := newMemoryWebhooks(webhook)
+	projects := &stubProjectRepo{project: entity.Project{ID: webhook.ProjectID, OwnerID: owner}}
+	service := NewWebhookService(repo, projects, server.Client()).(*WebhookServiceImpl)
+	service.Start()
+	defer service.Stop()
+
+	originalID := pendingDelivery(t, repo, webhook)
+	original, _ := repo.FindDelivery(originalID.String())
+	original.Status = entity.WebhookDeliveryFailed
+	repo.UpdateDelivery(original)
+
+	redelivery, err := service.Redeliver(webhook.ProjectID.String(), webhook.ID.String(), originalID.String(), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redelivery.ID == originalID || redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != originalID {
+		t.Fatalf("se esperaba una entrega nueva que apunte a la original, se obtuvo %+v", redelivery)
+	}
+
+	request := nextRequest(t, received)
+	if request.header.Get("X-Webhook-Delivery") != redelivery.ID.String() || string(request.body) != string(original.Payload) {
+		t.Fatalf("se recibió otra entrega: %v %s", request.header, request.body)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		delivery, _ := repo.FindDelivery(redelivery.ID.String())
+		if delivery.Status == entity.WebhookDeliverySucceeded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("la entrega reenviada no se completó: %+v", delivery)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if stored, _ := repo.FindDelivery(originalID.String()); stored.Status != entity.WebhookDeliveryFailed || stored.Attempts != 0 {
+		t.Fatalf("la entrega original cambió: %+v", stored)
+	}
+}
+
+// El cliente por defecto no conecta a direcciones internas y no reintenta
+func TestDefaultClientBlocksPrivateAddresses(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	webhook := testWebhook(server.URL)
+	repo := newMemoryWebhooks(webhook)
+	service := NewWebhookService(repo, &stubProjectRepo{}, nil).(*WebhookServiceImpl)
+	defer service.Stop()
+	id := pendingDelivery(t, repo, webhook)
+
+	service.deliver(id)
+
+	select {
+	case <-received:
+		t.Fatal("el receptor en loopback recibió la entrega")
+	default:
+	}
+	delivery, _ := repo.FindDelivery(id.String())
+	if delivery.Status != entity.WebhookDeliveryFailed || !strings.Contains(delivery.Error, errWebhookAddressBlocked.Error()) {
+		t.Fatalf("se esperaba la entrega fallida por la dirección, se obtuvo %+v", delivery)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for address, want := range map[string]bool{
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.100.100.200":  false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+	} {
+		if got := isPublicAddr(netip.MustParseAddr(address)); got != want {
+			t.Errorf("%s: se esperaba %v, se obtuvo %v", address, want, got)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/google/uuid"
+)
+
+type ChatService interface {
+	SendMessage(projectID string, userID uuid.UUID, username, text string) (*entity.ChatMessage, error)
+	// EditMessage y DeleteMessage solo se permiten al autor del mensaje
+	EditMessage(projectID, messageID string, userID uuid.UUID, text string) (*entity.ChatMessage, error)
+	DeleteMessage(projectID, messageID string, userID uuid.UUID) (*entity.ChatMessage, error)
+	GetHistory(projectID string, userID uuid.UUID, before string, limit int) (*dto.ChatHistoryResponse, error)
+	// GetRecent retorna los últimos mensajes del más viejo al más nuevo, sin verificar acceso
+	GetRecent(projectID string, limit int) ([]entity.ChatMessage, error)
+}
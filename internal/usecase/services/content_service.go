@@ -0,0 +1,17 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/google/uuid"
+)
+
+// ContentService lee y modifica subárboles del Content sin transferir el documento completo.
+// expectedRevision > 0 hace fallar la escritura si el proyecto cambió desde esa revisión.
+type ContentService interface {
+	Get(projectID string, userID uuid.UUID, target dto.ContentTarget) (*dto.ContentResponse, error)
+	Set(projectID string, userID uuid.UUID, target dto.ContentTarget, value json.RawMessage, expectedRevision int) (*dto.ContentResponse, error)
+	Delete(projectID string, userID uuid.UUID, target dto.ContentTarget, expectedRevision int) (*dto.ContentResponse, error)
+	InsertChild(projectID string, userID uuid.UUID, target dto.ContentTarget, in *dto.InsertNodeInput, expectedRevision int) (*dto.ContentResponse, error)
+}
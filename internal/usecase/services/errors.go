@@ -0,0 +1,17 @@
+package services
+
+import "errors"
+
+var (
+	ErrForbidden           = errors.New("forbidden")
+	ErrProjectNotPublished = errors.New("project is not published")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrUnknownEvent        = errors.New("unknown event type")
+	ErrNotABranch          = errors.New("project is not a branch of the target project")
+	ErrInvalidResolution   = errors.New("resolutions must be \"ours\" or \"theirs\"")
+	ErrInvalidNode         = errors.New("node must be a JSON object with a string id")
+	ErrRootNotDeletable    = errors.New("the document root cannot be deleted")
+	ErrInvalidTarget       = errors.New("the project owner and yourself cannot be moderated")
+	ErrInvalidDuration     = errors.New("duration must be between 1 second and 365 days")
+	ErrInvalidTicket       = errors.New("ticket is invalid, used or expired")
+)
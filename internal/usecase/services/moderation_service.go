@@ -0,0 +1,24 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/google/uuid"
+)
+
+// ModerationService administra los silencios y expulsiones de la sala de un proyecto. Solo
+// el dueño y los administradores pueden moderar; nadie puede moderar al dueño ni a sí mismo,
+// y solo el dueño puede moderar a un administrador. Cada acción queda registrada.
+type ModerationService interface {
+	// Mute silencia al usuario; duration 0 lo silencia hasta que se levante
+	Mute(projectID string, actorID, userID uuid.UUID, duration time.Duration, reason string) (*entity.RoomSanction, error)
+	Unmute(projectID string, actorID, userID uuid.UUID) error
+	// Kick solo verifica permisos y registra la acción; la desconexión la hace la sala
+	Kick(projectID string, actorID, userID uuid.UUID, reason string) error
+	Ban(projectID string, actorID, userID uuid.UUID, duration time.Duration, reason string) (*entity.RoomSanction, error)
+	Unban(projectID string, actorID, userID uuid.UUID) error
+	// ActiveSanction retorna la sanción vigente del tipo dado, o nil si el usuario no tiene
+	ActiveSanction(projectID string, userID uuid.UUID, kind string) (*entity.RoomSanction, error)
+	GetLog(projectID string, actorID uuid.UUID, limit int) ([]entity.ModerationLog, error)
+}
@@ -1,11 +1,39 @@
 package services
 
-import "github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
 
 type ProjectService interface {
 	CreateProject(project *entity.Project) error
 	GetProjectByID(id string) (*entity.Project, error)
 	GetAllProjects() ([]entity.Project, error)
 	UpdateProject(project *entity.Project) error
+	// SaveRoomContent guarda el documento de una sala en vivo; su evento lleva source "room".
+	// Si el proyecto ya no está en revision, porque se cambió fuera de la sala, retorna
+	// repositories.ErrRevisionConflict sin guardar.
+	SaveRoomContent(id string, content datatypes.JSON, revision int) (*entity.Project, error)
 	DeleteProject(id string) error
+
+	GetProjectVersion(id string, revision int) (*entity.ProjectVersion, error)
+	GetProjectVersions(id string) ([]entity.ProjectVersion, error)
+	// DiffProjectVersions compara dos revisiones; to = 0 usa la revisión actual y from = 0 la anterior a to
+	DiffProjectVersions(id string, from, to int) (*dto.ProjectDiffResponse, error)
+
+	CreateBranch(projectID string, userID uuid.UUID, name string) (*entity.Project, error)
+	GetBranches(projectID string) ([]entity.Project, error)
+	// MergeBranch integra la rama en el proyecto; si hay conflictos sin resolver no guarda nada
+	MergeBranch(projectID string, userID uuid.UUID, input *dto.MergeBranchInput) (*dto.MergeBranchResponse, error)
+
+	// GetUserRole retorna el rol del usuario en el proyecto, o "" si no tiene acceso
+	GetUserRole(projectID string, userID uuid.UUID) (string, error)
+	// GetAccessibleProjectIDs retorna los proyectos propios y aquellos donde el usuario es miembro
+	GetAccessibleProjectIDs(userID uuid.UUID) ([]string, error)
+	GetMembers(projectID string) ([]entity.ProjectMember, error)
+	AddMember(projectID string, actorID, userID uuid.UUID, role string) (*entity.ProjectMember, error)
+	RemoveMember(projectID string, actorID, userID uuid.UUID) error
 }
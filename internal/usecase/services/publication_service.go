@@ -0,0 +1,14 @@
+package services
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/google/uuid"
+)
+
+type PublicationService interface {
+	// Publish publica la revisión indicada (0 = revisión actual) y conserva el slug si ya existía
+	Publish(projectID string, userID uuid.UUID, revision int) (*entity.ProjectPublication, error)
+	Unpublish(projectID string, userID uuid.UUID) (*entity.ProjectPublication, error)
+	GetPublication(projectID string, userID uuid.UUID) (*entity.ProjectPublication, error)
+	GetPublishedBySlug(slug string) (*entity.ProjectPublication, error)
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TicketService emite y canjea los tickets de un solo uso con los que los navegadores abren
+// el WebSocket. Los tickets se guardan en la base, así que se pueden canjear en cualquier
+// instancia de la API.
+type TicketService interface {
+	// Issue retorna un ticket nuevo del usuario y cuándo vence
+	Issue(userID uuid.UUID, ttl time.Duration) (string, time.Time, error)
+	// Redeem consume el ticket y retorna su usuario; ErrInvalidTicket si no existe, ya se
+	// usó o venció
+	Redeem(value string) (uuid.UUID, error)
+}
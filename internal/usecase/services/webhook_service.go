@@ -0,0 +1,21 @@
+package services
+
+import (
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/dto"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/entity"
+	"github.com/Y2ktorrez/go-flutter-parcial2_api/internal/event"
+	"github.com/google/uuid"
+)
+
+type WebhookService interface {
+	CreateWebhook(projectID string, userID uuid.UUID, input *dto.CreateWebhookInput) (*dto.CreateWebhookResponse, error)
+	GetWebhooks(projectID string, userID uuid.UUID) ([]entity.Webhook, error)
+	DeleteWebhook(projectID, webhookID string, userID uuid.UUID) error
+	GetDeliveries(projectID, webhookID string, userID uuid.UUID) ([]entity.WebhookDelivery, error)
+	Redeliver(projectID, webhookID, deliveryID string, userID uuid.UUID) (*entity.WebhookDelivery, error)
+
+	// Dispatch crea las entregas para los webhooks suscritos al evento
+	Dispatch(e event.Event)
+	Start()
+	Stop()
+}